@@ -2,17 +2,25 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
 
 	"cluely/internal/agent"
 	"cluely/internal/config"
+	"cluely/internal/control"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "trigger" {
+		os.Exit(runTrigger(os.Args[2:]))
+	}
+
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 	log.Println("🚀 Starting Cluely Agent...")
 
@@ -69,3 +77,46 @@ func findConfigFile() string {
 
 	return ""
 }
+
+// runTrigger implements `cluely trigger capture [--note TEXT | TEXT...]`.
+// It asks the running agent to capture and analyze the screen right now;
+// bind it to vision.hot_key in xbindkeys or sxhkd.
+func runTrigger(args []string) int {
+	flags := flag.NewFlagSet("trigger", flag.ContinueOnError)
+	socket := flags.String("socket", "", "control socket path (default: agent.control_socket or $XDG_RUNTIME_DIR/cluely.sock)")
+	note := flags.String("note", "", "free-text note attached to the capture")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: cluely trigger capture [--socket PATH] [--note TEXT | TEXT...]")
+		flags.PrintDefaults()
+	}
+
+	if len(args) == 0 || args[0] != control.CommandCapture {
+		flags.Usage()
+		return 2
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	// Trailing words are a shorthand for --note
+	if *note == "" {
+		*note = strings.Join(flags.Args(), " ")
+	}
+
+	path := *socket
+	if path == "" {
+		if configPath := findConfigFile(); configPath != "" {
+			if cfg, err := config.Load(configPath); err == nil {
+				path = cfg.Agent.ControlSocket
+			}
+		}
+	}
+
+	if err := control.Send(path, control.Request{Command: control.CommandCapture, Note: *note}); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Capture failed: %v\n", err)
+		return 1
+	}
+
+	fmt.Println("📸 Capture requested")
+	return 0
+}
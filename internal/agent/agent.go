@@ -2,12 +2,17 @@ package agent
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
 	"sync"
+	"time"
 
 	"cluely/internal/ai"
 	"cluely/internal/audio"
 	"cluely/internal/config"
+	"cluely/internal/control"
 	"cluely/internal/ui"
 	"cluely/internal/vision"
 )
@@ -18,20 +23,45 @@ type Agent struct {
 	visionModule *vision.Module
 	aiModule     *ai.Module
 	uiServer     *ui.Server
+	control      *control.Server
+	history      *sessionHistory
+	correlator   *correlator
+	triggers     *triggerEngine
+	redactor     *redactor
+	hintSeq      int
 	wg           sync.WaitGroup
 }
 
 func New(cfg *config.Config) *Agent {
-	return &Agent{
+	a := &Agent{
 		cfg:          cfg,
 		audioModule:  audio.NewModule(cfg.Audio),
 		visionModule: vision.NewModule(cfg.Vision),
 		aiModule:     ai.NewModule(cfg.AI),
 		uiServer:     ui.NewServer(cfg.UI),
+		history:      newSessionHistory(cfg.Agent),
+		correlator:   newCorrelator(time.Duration(cfg.Agent.CombineWindowMs) * time.Millisecond),
 	}
+	a.control = control.NewServer(cfg.Agent.ControlSocket, a.handleControl)
+	return a
 }
 
 func (a *Agent) Start(ctx context.Context) error {
+	triggers, err := newTriggerEngine(a.cfg.Agent)
+	if err != nil {
+		return err
+	}
+	a.triggers = triggers
+
+	redactor, err := newRedactor(a.cfg.Agent.Redaction)
+	if err != nil {
+		return err
+	}
+	a.redactor = redactor
+	if !redactor.Enabled() {
+		log.Println("⚠️  Redaction disabled: screen text and transcripts reach the AI provider as is")
+	}
+
 	// Запускаем UI сервер
 	if a.cfg.UI.Enabled {
 		if err := a.uiServer.Start(); err != nil {
@@ -56,6 +86,22 @@ func (a *Agent) Start(ctx context.Context) error {
 		} else {
 			log.Println("✅ Vision Module started")
 		}
+
+		if upload := a.visionModule.UploadHandler(); upload != nil {
+			if a.cfg.UI.Enabled {
+				a.uiServer.Handle("/screenshot", upload)
+			} else {
+				log.Println("⚠️  Screenshot upload needs the UI server ([ui] enabled = true)")
+			}
+		}
+	}
+
+	// Управляющий сокет для горячей клавиши и `cluely trigger capture`
+	if err := a.control.Start(ctx); err != nil {
+		log.Printf("⚠️  Control socket failed: %v (continuing without on-demand capture)", err)
+	} else if a.cfg.Vision.HotKey != "" {
+		log.Printf("⌨️  Bind %s to `cluely trigger capture` in your hotkey daemon (sxhkd: %s)",
+			a.cfg.Vision.HotKey, control.SxhkdChord(a.cfg.Vision.HotKey))
 	}
 
 	// Проверяем AI
@@ -89,69 +135,240 @@ func (a *Agent) processingLoop(ctx context.Context) {
 			}
 			a.handleTranscript(ctx, transcript)
 
+		case partial := <-a.audioModule.PartialChannel():
+			if a.cfg.UI.Enabled {
+				a.uiServer.SendCaption(a.redactor.MaskCaption(partial))
+			}
+
 		case screenshot, ok := <-a.visionModule.ScreenshotChannel():
 			if !ok {
 				continue
 			}
 			a.handleScreenshot(ctx, screenshot)
+
+		case cmd := <-a.uiServer.Commands():
+			a.handleCommand(ctx, cmd)
+
+		case <-a.correlator.Expired():
+			for _, input := range a.correlator.Flush() {
+				a.analyze(ctx, input)
+			}
 		}
 	}
 }
 
-func (a *Agent) handleTranscript(ctx context.Context, text string) {
-	log.Printf("🎤 Transcript: %s", text)
+func (a *Agent) handleTranscript(ctx context.Context, transcript audio.Transcript) {
+	input := a.redact(ai.AnalysisInput{
+		TranscriptText: transcript.Text,
+		Speaker:        transcript.Speaker,
+	})
+	if input.Speaker != "" {
+		log.Printf("🎤 Transcript [%s]: %s", input.Speaker, input.TranscriptText)
+	} else {
+		log.Printf("🎤 Transcript: %s", input.TranscriptText)
+	}
 
-	input := ai.AnalysisInput{
-		TranscriptText: text,
-		Type:           "audio",
+	// Фраза завершена - живой субтитр заменяется итоговой репликой
+	if a.cfg.UI.Enabled {
+		a.uiServer.SendTranscript(input.Speaker, input.TranscriptText)
 	}
 
-	result, err := a.aiModule.Analyze(ctx, input)
-	if err != nil {
-		log.Printf("❌ AI analysis error: %v", err)
-		return
+	// Фраза вроде "покажи логи" запрашивает снимок экрана; он придет в окне корреляции
+	// и будет проанализирован вместе с этой фразой. Правила проверяются по исходному тексту.
+	if a.cfg.Vision.Enabled && !a.visionModule.Paused() {
+		if rule := a.triggers.Match(transcript.Text); rule != "" {
+			log.Printf("🎯 Trigger %q matched: %s", rule, input.TranscriptText)
+			a.requestCapture(ctx, vision.CaptureRequest{Trigger: rule})
+		}
 	}
 
-	log.Printf("🤖 AI Hint: %s", result.Hint)
+	for _, input := range a.correlator.AddTranscript(input) {
+		a.analyze(ctx, input)
+	}
+}
 
-	if a.cfg.UI.Enabled {
-		a.uiServer.SendHint(result.Hint)
+// handleControl выполняет команду из управляющего сокета
+func (a *Agent) handleControl(ctx context.Context, req control.Request) error {
+	switch req.Command {
+	case control.CommandCapture:
+		return a.captureNow(ctx, "hotkey", req.Note)
+	default:
+		return fmt.Errorf("unknown command %q", req.Command)
 	}
 }
 
-func (a *Agent) handleScreenshot(ctx context.Context, data []byte) {
-	log.Printf("📸 Screenshot captured: %d bytes", len(data))
+// captureNow снимает экран по явному запросу пользователя (горячая клавиша, кнопка в UI)
+func (a *Agent) captureNow(ctx context.Context, trigger, note string) error {
+	if !a.cfg.Vision.Enabled {
+		return errors.New("vision module is disabled")
+	}
+	if note != "" {
+		// Сама заметка попадет в журнал вместе со снимком, уже после маскирования
+		log.Printf("⌨️  Capture requested (%s) with a note (%d chars)", trigger, len([]rune(note)))
+	} else {
+		log.Printf("⌨️  Capture requested (%s)", trigger)
+	}
+	return a.visionModule.Capture(ctx, vision.CaptureRequest{Trigger: trigger, Note: note})
+}
+
+// handleCommand выполняет команду из UI и подтверждает ее клиенту с тем же ID
+func (a *Agent) handleCommand(ctx context.Context, cmd ui.Command) {
+	log.Printf("🖱️  UI command %s (%s)", cmd.Command, cmd.ID)
+
+	switch cmd.Command {
+	case ui.CommandPause, ui.CommandResume:
+		a.setPaused(cmd.Target, cmd.Command == ui.CommandPause)
+		a.uiServer.Ack(cmd, nil)
+
+	case ui.CommandCapture:
+		// Команда захвата может работать секунды - не задерживаем processingLoop
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			a.uiServer.Ack(cmd, a.captureNow(ctx, "ui", cmd.Text))
+		}()
+
+	case ui.CommandAsk:
+		input := a.redact(ai.AnalysisInput{Type: "question", Question: cmd.Text})
+		log.Printf("❓ Question: %s", input.Question)
+		hintID, err := a.analyze(ctx, input)
+		a.uiServer.AckHint(cmd, hintID, err)
 
-	ocrText, err := a.visionModule.ExtractText(ctx, data)
+	case ui.CommandFeedback:
+		a.uiServer.Ack(cmd, a.recordFeedback(cmd.HintID, cmd.Value))
+
+	case ui.CommandPin, ui.CommandUnpin:
+		a.uiServer.Ack(cmd, a.uiServer.PinHint(cmd.HintID, cmd.Command == ui.CommandPin))
+
+	default:
+		a.uiServer.Ack(cmd, fmt.Errorf("unknown command %q", cmd.Command))
+	}
+}
+
+// setPaused приостанавливает или возобновляет захват: target "audio", "vision" или пусто - оба
+func (a *Agent) setPaused(target string, paused bool) {
+	if target == "" || target == "audio" {
+		a.audioModule.SetPaused(paused)
+	}
+	if target == "" || target == "vision" {
+		a.visionModule.SetPaused(paused)
+	}
+	a.uiServer.SetStatus(a.audioModule.Paused(), a.visionModule.Paused())
+}
+
+// recordFeedback запоминает оценку подсказки в истории, чтобы модель учитывала ее дальше
+func (a *Agent) recordFeedback(hintID, value string) error {
+	hint, ok := a.uiServer.Hint(hintID)
+	if !ok {
+		return fmt.Errorf("hint %s is not available", hintID)
+	}
+
+	if value == ui.FeedbackUseful {
+		log.Printf("👍 Hint #%s marked useful (%s)", hintID, hint.Provider)
+	} else {
+		log.Printf("👎 Hint #%s marked wrong (%s)", hintID, hint.Provider)
+	}
+	a.history.Add(ai.HistoryEntry{Kind: "hint_" + value, Text: hint.Hint})
+	return nil
+}
+
+// requestCapture запрашивает снимок в фоне, чтобы команда захвата не задерживала processingLoop
+func (a *Agent) requestCapture(ctx context.Context, req vision.CaptureRequest) {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		if err := a.visionModule.Capture(ctx, req); err != nil {
+			log.Printf("⚠️  Capture (%s) failed: %v", req.Trigger, err)
+		}
+	}()
+}
+
+func (a *Agent) handleScreenshot(ctx context.Context, shot vision.Screenshot) {
+	log.Printf("📸 Screenshot captured: %s %dx%d, %d bytes, app %q (%s, %s)",
+		shot.Format, shot.Width, shot.Height, len(shot.Data), shot.App, shot.Source, shot.CapturedAt.Format("15:04:05"))
+	if shot.Trigger != "" {
+		log.Printf("🎯 Screenshot requested by %s", shot.Trigger)
+	}
+
+	result, err := a.visionModule.Recognize(ctx, shot)
 	if err != nil {
 		log.Printf("❌ OCR error: %v", err)
 		return
 	}
 
-	log.Printf("📝 OCR Text: %s", ocrText)
+	// Таблицы с экрана уходят в промпт markdown таблицами, чтобы модель видела колонки
+	input := a.redact(ai.AnalysisInput{
+		OCRText: result.Layout(),
+		Note:    shot.Note,
+		App:     shot.App,
+	})
+	if input.Note != "" {
+		log.Printf("📝 Note: %s", input.Note)
+	}
+	log.Printf("📝 OCR Text (%d lines, %d tables, confidence %.2f):\n%s",
+		len(result.Lines), result.Tables(), result.Confidence, input.OCRText)
 
-	input := ai.AnalysisInput{
-		OCRText: ocrText,
-		Type:    "vision",
+	for _, input := range a.correlator.AddOCR(input) {
+		a.analyze(ctx, input)
 	}
+}
 
-	result, err := a.aiModule.Analyze(ctx, input)
+// redact маскирует секреты и персональные данные сразу при поступлении текста, чтобы исходные
+// значения не попали ни в журнал, ни в UI, ни в историю, ни к провайдеру. В журнал аудита
+// пишется только число замен.
+func (a *Agent) redact(input ai.AnalysisInput) ai.AnalysisInput {
+	input, redacted := a.redactor.RedactInput(input)
+	if len(redacted) > 0 {
+		log.Printf("🛡️  Redacted: %s", formatRedactionCounts(redacted))
+	}
+	return input
+}
+
+// analyze запускает потоковый AI анализ и отправляет частичные подсказки в UI по мере генерации.
+// Возвращает ID подсказки, под которым она ушла в UI. Текст input уже прошел через redact.
+func (a *Agent) analyze(ctx context.Context, input ai.AnalysisInput) (string, error) {
+	input.History = a.history.Snapshot()
+	a.history.Add(ai.HistoryEntry{Kind: "transcript", Speaker: input.Speaker, Text: input.TranscriptText})
+	a.history.Add(ai.HistoryEntry{Kind: "ocr", Text: input.OCRText})
+	a.history.Add(ai.HistoryEntry{Kind: "question", Text: input.Question})
+
+	if input.Type == "combined" {
+		log.Println("🔗 Combined analysis: transcript + screenshot")
+	}
+
+	a.hintSeq++
+	hintID := fmt.Sprintf("%d", a.hintSeq)
+
+	var partial strings.Builder
+	result, err := a.aiModule.AnalyzeStream(ctx, input, func(chunk string) {
+		partial.WriteString(chunk)
+		if a.cfg.UI.Enabled {
+			a.uiServer.SendPartialHint(hintID, input.Type, partial.String())
+		}
+	})
 	if err != nil {
 		log.Printf("❌ AI analysis error: %v", err)
-		return
+		// Страница уже могла показать частичный текст - завершаем его
+		if a.cfg.UI.Enabled && partial.Len() > 0 {
+			a.uiServer.SendHintError(hintID, input.Type, err)
+		}
+		return "", err
 	}
 
-	log.Printf("🤖 AI Hint: %s", result.Hint)
+	log.Printf("🤖 AI Hint (%s): %s", result.Provider, result.Hint)
+	a.history.Add(ai.HistoryEntry{Kind: "hint", Text: result.Hint})
 
 	if a.cfg.UI.Enabled {
-		a.uiServer.SendHint(result.Hint)
+		a.uiServer.SendHint(hintID, input, result)
 	}
+	return hintID, nil
 }
 
 func (a *Agent) Stop() {
 	log.Println("🛑 Stopping modules...")
 
 	a.audioModule.Stop()
+	a.control.Stop()
 	a.visionModule.Stop()
 	a.uiServer.Stop()
 
@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"cluely/internal/audio"
+	"cluely/internal/config"
+)
+
+func TestTranscriptIsRedactedBeforeLogging(t *testing.T) {
+	cfg := &config.Config{
+		AI: config.AIConfig{Provider: "mock"},
+		Agent: config.AgentConfig{
+			HistoryMaxEntries: 10,
+			Redaction:         config.RedactionConfig{Enabled: true},
+		},
+	}
+	a := New(cfg)
+	redactor, err := newRedactor(cfg.Agent.Redaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.redactor = redactor
+	ctx := context.Background()
+	if err := a.aiModule.Initialize(ctx); err != nil {
+		t.Fatalf("AI module: %v", err)
+	}
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	a.handleTranscript(ctx, audio.Transcript{Text: "подключись к 10.20.30.40 с password=hunter2", Speaker: "S1"})
+
+	for _, secret := range []string{"10.20.30.40", "hunter2"} {
+		if strings.Contains(logs.String(), secret) {
+			t.Errorf("log contains %q:\n%s", secret, logs.String())
+		}
+		for _, entry := range a.history.Snapshot() {
+			if strings.Contains(entry.Text, secret) {
+				t.Errorf("history contains %q: %+v", secret, entry)
+			}
+		}
+	}
+	if !strings.Contains(logs.String(), "🎤 Transcript [S1]: подключись к [IP_1] с password=[PASSWORD_1]") {
+		t.Errorf("redacted transcript not logged:\n%s", logs.String())
+	}
+	if !strings.Contains(logs.String(), "🛡️  Redacted: ip=1, password=1") {
+		t.Errorf("audit counts not logged:\n%s", logs.String())
+	}
+}
@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"time"
+
+	"cluely/internal/ai"
+)
+
+// correlator объединяет транскрипцию и OCR текст, пришедшие в пределах окна, в один combined анализ.
+// Первое событие пары придерживается до конца окна; если пара не нашлась, оно анализируется отдельно.
+// Используется только из processingLoop, поэтому не требует синхронизации.
+type correlator struct {
+	window     time.Duration
+	transcript *ai.AnalysisInput // ожидающая пары транскрипция (Type "audio")
+	screen     *ai.AnalysisInput // ожидающий пары текст с экрана (Type "vision")
+	timer      *time.Timer
+}
+
+func newCorrelator(window time.Duration) *correlator {
+	return &correlator{window: window}
+}
+
+// Expired возвращает канал, срабатывающий по истечении окна ожидания пары (nil, если ждать нечего)
+func (c *correlator) Expired() <-chan time.Time {
+	if c.timer == nil {
+		return nil
+	}
+	return c.timer.C
+}
+
+// AddTranscript регистрирует транскрипцию (TranscriptText, Speaker) и возвращает вводы, готовые к анализу
+func (c *correlator) AddTranscript(input ai.AnalysisInput) []ai.AnalysisInput {
+	input.Type = "audio"
+	if c.window <= 0 {
+		return []ai.AnalysisInput{input}
+	}
+
+	if c.screen != nil {
+		combined := combine(input, *c.screen)
+		c.reset()
+		return []ai.AnalysisInput{combined}
+	}
+
+	// Предыдущая транскрипция так и не дождалась пары - анализируем ее отдельно
+	ready := c.Flush()
+	c.transcript = &input
+	c.timer = time.NewTimer(c.window)
+	return ready
+}
+
+// AddOCR регистрирует текст со скриншота (OCRText, Note, App) и возвращает вводы, готовые к анализу
+func (c *correlator) AddOCR(input ai.AnalysisInput) []ai.AnalysisInput {
+	input.Type = "vision"
+	if c.window <= 0 {
+		return []ai.AnalysisInput{input}
+	}
+
+	if c.transcript != nil {
+		combined := combine(*c.transcript, input)
+		c.reset()
+		return []ai.AnalysisInput{combined}
+	}
+
+	ready := c.Flush()
+	c.screen = &input
+	c.timer = time.NewTimer(c.window)
+	return ready
+}
+
+// Flush возвращает ожидающее событие как одиночный ввод
+func (c *correlator) Flush() []ai.AnalysisInput {
+	var ready []ai.AnalysisInput
+	if c.transcript != nil {
+		ready = append(ready, *c.transcript)
+	}
+	if c.screen != nil {
+		ready = append(ready, *c.screen)
+	}
+	c.reset()
+	return ready
+}
+
+func (c *correlator) reset() {
+	c.transcript = nil
+	c.screen = nil
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+}
+
+// combine собирает combined ввод из транскрипции и текста с экрана
+func combine(transcript, screen ai.AnalysisInput) ai.AnalysisInput {
+	screen.TranscriptText = transcript.TranscriptText
+	screen.Speaker = transcript.Speaker
+	screen.Type = "combined"
+	return screen
+}
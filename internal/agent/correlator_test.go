@@ -0,0 +1,159 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"cluely/internal/ai"
+)
+
+func transcriptInput(text string) ai.AnalysisInput {
+	return ai.AnalysisInput{TranscriptText: text, Speaker: "S1"}
+}
+
+func screenInput(text string) ai.AnalysisInput {
+	return ai.AnalysisInput{OCRText: text, App: "Terminal", Note: "note"}
+}
+
+func TestCorrelatorPairing(t *testing.T) {
+	combined := ai.AnalysisInput{Type: "combined", TranscriptText: "поды падают", Speaker: "S1", OCRText: "CrashLoopBackOff", App: "Terminal", Note: "note"}
+
+	tests := []struct {
+		name string
+		add  func(c *correlator) []ai.AnalysisInput
+		want []ai.AnalysisInput
+	}{
+		{
+			name: "transcript then screen",
+			add: func(c *correlator) []ai.AnalysisInput {
+				if ready := c.AddTranscript(transcriptInput("поды падают")); len(ready) != 0 {
+					t.Errorf("first event released: %+v", ready)
+				}
+				return c.AddOCR(screenInput("CrashLoopBackOff"))
+			},
+			want: []ai.AnalysisInput{combined},
+		},
+		{
+			name: "screen then transcript",
+			add: func(c *correlator) []ai.AnalysisInput {
+				if ready := c.AddOCR(screenInput("CrashLoopBackOff")); len(ready) != 0 {
+					t.Errorf("first event released: %+v", ready)
+				}
+				return c.AddTranscript(transcriptInput("поды падают"))
+			},
+			want: []ai.AnalysisInput{combined},
+		},
+		{
+			// Вторая транскрипция без экрана вытесняет первую, та анализируется отдельно
+			name: "two transcripts",
+			add: func(c *correlator) []ai.AnalysisInput {
+				c.AddTranscript(transcriptInput("первая"))
+				return c.AddTranscript(transcriptInput("вторая"))
+			},
+			want: []ai.AnalysisInput{{Type: "audio", TranscriptText: "первая", Speaker: "S1"}},
+		},
+		{
+			name: "two screens",
+			add: func(c *correlator) []ai.AnalysisInput {
+				c.AddOCR(screenInput("первый"))
+				return c.AddOCR(screenInput("второй"))
+			},
+			want: []ai.AnalysisInput{{Type: "vision", OCRText: "первый", App: "Terminal", Note: "note"}},
+		},
+		{
+			// Ожидает только вторая транскрипция, и она объединяется с экраном
+			name: "screen pairs with the latest transcript",
+			add: func(c *correlator) []ai.AnalysisInput {
+				c.AddTranscript(transcriptInput("первая"))
+				c.AddTranscript(transcriptInput("поды падают"))
+				return c.AddOCR(screenInput("CrashLoopBackOff"))
+			},
+			want: []ai.AnalysisInput{combined},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCorrelator(time.Minute)
+			if got := tt.add(c); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ready = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCorrelatorPairResetsState(t *testing.T) {
+	c := newCorrelator(time.Minute)
+	c.AddTranscript(transcriptInput("поды падают"))
+	c.AddOCR(screenInput("CrashLoopBackOff"))
+
+	if c.Expired() != nil {
+		t.Error("timer still running after a pair")
+	}
+	if ready := c.Flush(); len(ready) != 0 {
+		t.Errorf("Flush after a pair = %+v", ready)
+	}
+	// Следующая пара собирается заново, а не из остатков предыдущей
+	if ready := c.AddOCR(screenInput("Running")); len(ready) != 0 {
+		t.Errorf("new screen released: %+v", ready)
+	}
+}
+
+func TestCorrelatorWindowExpires(t *testing.T) {
+	c := newCorrelator(30 * time.Millisecond)
+	if c.Expired() != nil {
+		t.Fatal("Expired without pending events")
+	}
+
+	c.AddTranscript(transcriptInput("поды падают"))
+	select {
+	case <-c.Expired():
+	case <-time.After(time.Second):
+		t.Fatal("window did not expire")
+	}
+
+	// Как в processingLoop: по истечении окна событие анализируется отдельно
+	want := []ai.AnalysisInput{{Type: "audio", TranscriptText: "поды падают", Speaker: "S1"}}
+	if ready := c.Flush(); !reflect.DeepEqual(ready, want) {
+		t.Errorf("Flush = %+v, want %+v", ready, want)
+	}
+	if c.Expired() != nil {
+		t.Error("timer left after Flush")
+	}
+
+	// Экран после окна уже не объединяется с транскрипцией
+	if ready := c.AddOCR(screenInput("CrashLoopBackOff")); len(ready) != 0 {
+		t.Errorf("late screen released: %+v", ready)
+	}
+}
+
+func TestCorrelatorWindowRestartsForNewEvent(t *testing.T) {
+	c := newCorrelator(60 * time.Millisecond)
+	c.AddTranscript(transcriptInput("первая"))
+	time.Sleep(40 * time.Millisecond)
+	c.AddTranscript(transcriptInput("вторая"))
+
+	// Окно второй транскрипции отсчитывается от нее, а не от первой
+	select {
+	case <-c.Expired():
+		t.Fatal("window of the new event expired early")
+	case <-time.After(35 * time.Millisecond):
+	}
+	if ready := c.AddOCR(screenInput("CrashLoopBackOff")); len(ready) != 1 || ready[0].Type != "combined" || ready[0].TranscriptText != "вторая" {
+		t.Errorf("ready = %+v, want the second transcript combined", ready)
+	}
+}
+
+func TestCorrelatorDisabled(t *testing.T) {
+	c := newCorrelator(0)
+	if ready := c.AddTranscript(transcriptInput("поды падают")); len(ready) != 1 || ready[0].Type != "audio" {
+		t.Errorf("transcript = %+v, want it analyzed at once", ready)
+	}
+	if ready := c.AddOCR(screenInput("CrashLoopBackOff")); len(ready) != 1 || ready[0].Type != "vision" {
+		t.Errorf("screen = %+v, want it analyzed at once", ready)
+	}
+	if c.Expired() != nil {
+		t.Error("timer started without a window")
+	}
+}
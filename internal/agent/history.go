@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"cluely/internal/ai"
+	"cluely/internal/config"
+)
+
+const (
+	defaultHistoryMaxEntries = 20
+	defaultHistoryMaxTokens  = 1000
+	defaultHistoryWindow     = 5 * time.Minute
+
+	// minHistoryExcerptTokens - меньше этого остаток бюджета на обрезанную запись не тратится
+	minHistoryExcerptTokens = 16
+)
+
+// sessionHistory хранит последние транскрипции, OCR тексты и подсказки в скользящем окне.
+// Данные живут только в памяти и теряются при остановке агента (NFR-2).
+type sessionHistory struct {
+	mu         sync.Mutex
+	entries    []ai.HistoryEntry
+	maxEntries int
+	maxTokens  int
+	window     time.Duration
+}
+
+func newSessionHistory(cfg config.AgentConfig) *sessionHistory {
+	h := &sessionHistory{
+		maxEntries: cfg.HistoryMaxEntries,
+		maxTokens:  cfg.HistoryMaxTokens,
+		window:     time.Duration(cfg.HistoryWindowSec) * time.Second,
+	}
+
+	if h.maxEntries <= 0 {
+		h.maxEntries = defaultHistoryMaxEntries
+	}
+	if h.maxTokens <= 0 {
+		h.maxTokens = defaultHistoryMaxTokens
+	}
+	if h.window <= 0 {
+		h.window = defaultHistoryWindow
+	}
+
+	return h
+}
+
+// Add добавляет событие в историю, вытесняя самые старые записи
+func (h *sessionHistory) Add(entry ai.HistoryEntry) {
+	if entry.Text == "" {
+		return
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > h.maxEntries {
+		h.entries = h.entries[len(h.entries)-h.maxEntries:]
+	}
+}
+
+// Snapshot возвращает актуальные записи от старых к новым, укладывающиеся в бюджет токенов.
+// Записи набираются от новых к старым. Запись, которая не помещается целиком (большой текст
+// с экрана), обрезается до остатка бюджета, а если остаток слишком мал - пропускается,
+// и место достается более старым коротким записям.
+func (h *sessionHistory) Snapshot() []ai.HistoryEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.expire(time.Now())
+
+	budget := h.maxTokens
+	snapshot := make([]ai.HistoryEntry, 0, len(h.entries))
+	for i := len(h.entries) - 1; i >= 0 && budget > 0; i-- {
+		entry := h.entries[i]
+		cost := estimateTokens(entry.Text)
+		if cost > budget {
+			if budget < minHistoryExcerptTokens {
+				continue
+			}
+			entry.Text = truncateTokens(entry.Text, budget)
+			cost = budget
+		}
+		budget -= cost
+		snapshot = append(snapshot, entry)
+	}
+
+	for i, j := 0, len(snapshot)-1; i < j; i, j = i+1, j-1 {
+		snapshot[i], snapshot[j] = snapshot[j], snapshot[i]
+	}
+	return snapshot
+}
+
+// expire удаляет записи старше окна истории
+func (h *sessionHistory) expire(now time.Time) {
+	cutoff := now.Add(-h.window)
+	i := 0
+	for i < len(h.entries) && h.entries[i].Time.Before(cutoff) {
+		i++
+	}
+	h.entries = h.entries[i:]
+}
+
+// truncateTokens обрезает текст так, чтобы вместе с многоточием он занимал не больше tokens
+func truncateTokens(text string, tokens int) string {
+	runes := []rune(text)
+	keep := (tokens-1)*4 - 1
+	if keep >= len(runes) {
+		return text
+	}
+	return string(runes[:max(keep, 0)]) + "…"
+}
+
+// estimateTokens грубо оценивает число токенов: около четырех символов на токен
+func estimateTokens(text string) int {
+	return utf8.RuneCountInString(text)/4 + 1
+}
@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"cluely/internal/ai"
+	"cluely/internal/config"
+)
+
+// texts возвращает тексты записей по порядку
+func texts(entries []ai.HistoryEntry) []string {
+	result := make([]string, len(entries))
+	for i, entry := range entries {
+		result[i] = entry.Text
+	}
+	return result
+}
+
+func TestHistorySnapshotBudget(t *testing.T) {
+	// Текст из 4n-4 символов стоит n токенов
+	text := func(name string, tokens int) string {
+		return name + strings.Repeat(".", (tokens-1)*4-len(name))
+	}
+
+	tests := []struct {
+		name    string
+		entries []string
+		budget  int
+		want    []string
+	}{
+		{
+			name:    "all fit",
+			entries: []string{text("a", 10), text("b", 10)},
+			budget:  20,
+			want:    []string{text("a", 10), text("b", 10)},
+		},
+		{
+			name:    "oldest dropped",
+			entries: []string{text("a", 10), text("b", 10), text("c", 10)},
+			budget:  25,
+			want:    []string{text("b", 10), text("c", 10)},
+		},
+		{
+			// Большая запись посередине больше не отрезает все, что старше нее
+			name:    "oversized entry skipped",
+			entries: []string{text("a", 5), text("ocr", 500), text("b", 10)},
+			budget:  20,
+			want:    []string{text("a", 5), text("b", 10)},
+		},
+		{
+			// Новейшая запись больше всего бюджета - в историю попадает ее начало
+			name:    "newest entry truncated",
+			entries: []string{text("a", 5), text("ocr", 500)},
+			budget:  40,
+			want:    []string{text("ocr", 500)[:39*4-1] + "…"},
+		},
+		{
+			name:    "truncated to the remaining budget",
+			entries: []string{text("a", 5), text("ocr", 500), text("b", 10)},
+			budget:  40,
+			want:    []string{text("ocr", 500)[:29*4-1] + "…", text("b", 10)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newSessionHistory(config.AgentConfig{HistoryMaxTokens: tt.budget})
+			for _, text := range tt.entries {
+				h.Add(ai.HistoryEntry{Kind: "transcript", Text: text})
+			}
+
+			got := h.Snapshot()
+			if strings.Join(texts(got), "|") != strings.Join(tt.want, "|") {
+				t.Errorf("snapshot = %q\nwant %q", texts(got), tt.want)
+			}
+			total := 0
+			for _, entry := range got {
+				total += estimateTokens(entry.Text)
+			}
+			if total > tt.budget {
+				t.Errorf("snapshot costs %d tokens, budget %d", total, tt.budget)
+			}
+		})
+	}
+}
+
+func TestHistoryTruncationKeepsRunes(t *testing.T) {
+	h := newSessionHistory(config.AgentConfig{HistoryMaxTokens: 20})
+	h.Add(ai.HistoryEntry{Kind: "ocr", Text: strings.Repeat("Ж", 400)})
+
+	got := h.Snapshot()
+	if len(got) != 1 || !utf8.ValidString(got[0].Text) || !strings.HasSuffix(got[0].Text, "…") {
+		t.Fatalf("snapshot = %+v", got)
+	}
+	if cost := estimateTokens(got[0].Text); cost != 20 {
+		t.Errorf("truncated entry costs %d tokens, want the whole budget", cost)
+	}
+	// Сама история не меняется
+	if h.entries[0].Text != strings.Repeat("Ж", 400) {
+		t.Error("stored entry was truncated")
+	}
+}
+
+func TestHistoryLimits(t *testing.T) {
+	h := newSessionHistory(config.AgentConfig{HistoryMaxEntries: 3, HistoryWindowSec: 60})
+	now := time.Now()
+	h.Add(ai.HistoryEntry{Kind: "transcript", Text: "устарело", Time: now.Add(-2 * time.Minute)})
+	for _, text := range []string{"раз", "два", "три", "четыре"} {
+		h.Add(ai.HistoryEntry{Kind: "transcript", Text: text})
+	}
+	h.Add(ai.HistoryEntry{Kind: "transcript"})
+
+	if got := strings.Join(texts(h.Snapshot()), " "); got != "два три четыре" {
+		t.Errorf("snapshot = %q, want the last three entries", got)
+	}
+
+	// Окно отбрасывает старые записи при чтении
+	h.entries[0].Time = now.Add(-2 * time.Minute)
+	if got := strings.Join(texts(h.Snapshot()), " "); got != "три четыре" {
+		t.Errorf("snapshot = %q, want entries within the window", got)
+	}
+}
@@ -0,0 +1,295 @@
+package agent
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"cluely/internal/ai"
+	"cluely/internal/config"
+)
+
+const (
+	defaultEntropyMinLength = 20
+	defaultEntropyThreshold = 4.0
+	// Сколько разных значений помнит redactor; при переполнении таблица сбрасывается,
+	// а счетчики продолжаются, поэтому плейсхолдеры не повторяются
+	maxRedactedValues = 10000
+)
+
+// Встроенные наборы правил для agent.redaction.rule_sets. Если в шаблоне есть группа,
+// заменяется только она: "password=hunter2" -> "password=[PASSWORD_1]".
+var redactionRuleSets = map[string][]config.RedactionRule{
+	"secrets": {
+		{Name: "private_key", Pattern: `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`},
+		{Name: "aws_access_key", Pattern: `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`},
+		{Name: "aws_secret_key", Pattern: `(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})`},
+		{Name: "jwt", Pattern: `\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`},
+		{Name: "bearer_token", Pattern: `(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`},
+		{Name: "github_token", Pattern: `\bgh[pousr]_[A-Za-z0-9]{36,}\b`},
+		{Name: "slack_token", Pattern: `\bxox[abprs]-[A-Za-z0-9-]{10,}`},
+		// Пароль в строке подключения: postgres://user:secret@db:5432
+		{Name: "url_password", Pattern: `[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]+:([^/\s@\[]+)@`},
+		{Name: "password", Pattern: `(?i)(?:^|[^a-z])(?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key)["']?\s*[:=]\s*["']?([^\s"',;\[][^\s"',;]*)`},
+	},
+	"pii": {
+		{Name: "email", Pattern: `\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`},
+	},
+	"network": {
+		{Name: "ip", Pattern: `\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`},
+	},
+}
+
+// entropyRuleSet - набор, включающий поиск случайных строк (ключи и токены неизвестного формата)
+const entropyRuleSet = "entropy"
+
+// Кандидаты для поиска по энтропии: '/', '-' и '.' разделяют, чтобы не трогать пути и имена подов
+var entropyCandidate = regexp.MustCompile(`[A-Za-z0-9+=_]+`)
+
+// redactionRule - скомпилированное правило маскирования
+type redactionRule struct {
+	name    string
+	label   string // префикс плейсхолдера: "aws_access_key" -> [AWS_ACCESS_KEY_1]
+	pattern *regexp.Regexp
+}
+
+// redactor вырезает секреты и персональные данные из текста до отправки в AI провайдер.
+// Одно и то же значение в пределах сессии всегда получает один плейсхолдер, поэтому модель
+// видит, что в логе и в реплике речь об одном адресе, не зная самого адреса.
+// Таблица значений живет только в памяти (NFR-2).
+type redactor struct {
+	rules            []redactionRule
+	entropy          bool
+	entropyMinLength int
+	entropyThreshold float64
+
+	mu           sync.Mutex
+	placeholders map[string]string // значение -> плейсхолдер
+	counters     map[string]int    // label -> последний номер
+}
+
+func newRedactor(cfg config.RedactionConfig) (*redactor, error) {
+	r := &redactor{
+		entropyMinLength: cfg.EntropyMinLength,
+		entropyThreshold: cfg.EntropyThreshold,
+		placeholders:     make(map[string]string),
+		counters:         make(map[string]int),
+	}
+	if !cfg.Enabled {
+		return r, nil
+	}
+	if r.entropyMinLength <= 0 {
+		r.entropyMinLength = defaultEntropyMinLength
+	}
+	if r.entropyThreshold <= 0 {
+		r.entropyThreshold = defaultEntropyThreshold
+	}
+
+	ruleSets := cfg.RuleSets
+	if len(ruleSets) == 0 {
+		ruleSets = []string{"secrets", entropyRuleSet, "pii", "network"}
+	}
+
+	// Пользовательские правила идут первыми: они точнее встроенных
+	rules := append([]config.RedactionRule(nil), cfg.Rules...)
+	for _, set := range ruleSets {
+		if set == entropyRuleSet {
+			r.entropy = true
+			continue
+		}
+		builtin, ok := redactionRuleSets[set]
+		if !ok {
+			return nil, fmt.Errorf("unknown redaction rule set %q", set)
+		}
+		rules = append(rules, builtin...)
+	}
+
+	for i, rule := range rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("rule-%d", i+1)
+		}
+		if rule.Pattern == "" {
+			return nil, fmt.Errorf("redaction rule %q has no pattern", name)
+		}
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redaction rule %q: invalid pattern: %w", name, err)
+		}
+		r.rules = append(r.rules, redactionRule{name: name, label: placeholderLabel(name), pattern: pattern})
+	}
+
+	return r, nil
+}
+
+// Enabled сообщает, есть ли хоть одно правило
+func (r *redactor) Enabled() bool {
+	return len(r.rules) > 0 || r.entropy
+}
+
+// RedactInput маскирует все текстовые поля ввода и возвращает число замен по правилам
+func (r *redactor) RedactInput(input ai.AnalysisInput) (ai.AnalysisInput, map[string]int) {
+	counts := make(map[string]int)
+	input.TranscriptText = r.redact(input.TranscriptText, counts)
+	input.OCRText = r.redact(input.OCRText, counts)
+	input.Note = r.redact(input.Note, counts)
+	input.Question = r.redact(input.Question, counts)
+	return input, counts
+}
+
+func (r *redactor) redact(text string, counts map[string]int) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.apply(text, func(name, label, value string) string {
+		counts[name]++
+		return r.placeholder(label, value)
+	})
+}
+
+// MaskCaption маскирует живой субтитр. Промежуточный текст обрывается на полуслове
+// ("password=hun"), поэтому плейсхолдеры без номеров: обрывки не занимают номера сессии,
+// и итоговая реплика получит тот же плейсхолдер, что и без субтитров.
+func (r *redactor) MaskCaption(text string) string {
+	return r.apply(text, func(name, label, value string) string {
+		return "[" + label + "]"
+	})
+}
+
+// apply заменяет найденные правилами значения результатом replace
+func (r *redactor) apply(text string, replace func(name, label, value string) string) string {
+	if text == "" || !r.Enabled() {
+		return text
+	}
+
+	for _, rule := range r.rules {
+		text = replaceMatches(text, rule.pattern, func(value string) string {
+			return replace(rule.name, rule.label, value)
+		})
+	}
+
+	if r.entropy {
+		text = entropyCandidate.ReplaceAllStringFunc(text, func(token string) string {
+			if !r.looksRandom(token) {
+				return token
+			}
+			return replace(entropyRuleSet, "SECRET", token)
+		})
+	}
+
+	return text
+}
+
+// placeholder возвращает стабильный плейсхолдер значения; вызывается под r.mu
+func (r *redactor) placeholder(label, value string) string {
+	key := label + "\x00" + value
+	if placeholder, ok := r.placeholders[key]; ok {
+		return placeholder
+	}
+	if len(r.placeholders) >= maxRedactedValues {
+		r.placeholders = make(map[string]string)
+	}
+
+	r.counters[label]++
+	placeholder := fmt.Sprintf("[%s_%d]", label, r.counters[label])
+	r.placeholders[key] = placeholder
+	return placeholder
+}
+
+// looksRandom отличает сгенерированные ключи от идентификаторов: длинная строка со смешанным
+// регистром и цифрами, энтропия Шеннона которой не ниже порога (бит на символ).
+// Hex строки сюда не попадают намеренно: это в основном хеши коммитов, образов и контейнеров,
+// а их энтропия не превышает 4 бит на символ. Hex ключи закрываются правилами agent.redaction.rules.
+func (r *redactor) looksRandom(token string) bool {
+	if len(token) < r.entropyMinLength {
+		return false
+	}
+
+	var upper, lower, digit bool
+	for _, c := range token {
+		switch {
+		case unicode.IsUpper(c):
+			upper = true
+		case unicode.IsLower(c):
+			lower = true
+		case unicode.IsDigit(c):
+			digit = true
+		}
+	}
+	if !upper || !lower || !digit {
+		return false
+	}
+
+	return shannonEntropy(token) >= r.entropyThreshold
+}
+
+func shannonEntropy(s string) float64 {
+	freq := make(map[rune]int)
+	n := 0
+	for _, c := range s {
+		freq[c]++
+		n++
+	}
+
+	var entropy float64
+	for _, count := range freq {
+		p := float64(count) / float64(n)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// replaceMatches заменяет совпадения шаблона; если в шаблоне есть группа и она совпала,
+// заменяется только первая группа, а окружающий контекст (имя параметра) остается
+func replaceMatches(text string, pattern *regexp.Regexp, replace func(value string) string) string {
+	matches := pattern.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		if start == end {
+			continue
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(replace(text[start:end]))
+		last = end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// placeholderLabel делает из имени правила префикс плейсхолдера: "aws-key" -> "AWS_KEY"
+func placeholderLabel(name string) string {
+	return strings.Map(func(c rune) rune {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			return unicode.ToUpper(c)
+		}
+		return '_'
+	}, name)
+}
+
+// formatRedactionCounts собирает счетчики для аудита: "email=2, ip=1" (значения не выводятся)
+func formatRedactionCounts(counts map[string]int) string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%d", name, counts[name])
+	}
+	return strings.Join(parts, ", ")
+}
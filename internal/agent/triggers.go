@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"cluely/internal/config"
+)
+
+const defaultTriggerCooldown = 10 * time.Second
+
+// triggerRule - скомпилированное правило из agent.triggers
+type triggerRule struct {
+	name     string
+	keywords []string
+	pattern  *regexp.Regexp
+	priority int
+	cooldown time.Duration
+	lastFire time.Time
+}
+
+// triggerEngine ищет в транскрипциях фразы вроде "покажи логи" и решает, нужен ли снимок экрана.
+// Из совпавших правил срабатывает правило с наибольшим приоритетом. После срабатывания правила
+// с тем же или меньшим приоритетом ждут общий cooldown, более приоритетные могут его прервать.
+// Используется только из processingLoop, поэтому не требует синхронизации.
+type triggerEngine struct {
+	rules        []*triggerRule
+	cooldown     time.Duration
+	lastFire     time.Time
+	lastPriority int
+}
+
+func newTriggerEngine(cfg config.AgentConfig) (*triggerEngine, error) {
+	e := &triggerEngine{
+		cooldown: time.Duration(cfg.TriggerCooldownSec) * time.Second,
+	}
+	if e.cooldown <= 0 {
+		e.cooldown = defaultTriggerCooldown
+	}
+
+	for i, rule := range cfg.Triggers {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("trigger-%d", i+1)
+		}
+
+		compiled := &triggerRule{
+			name:     name,
+			priority: rule.Priority,
+			cooldown: time.Duration(rule.CooldownSec) * time.Second,
+		}
+		for _, keyword := range rule.Keywords {
+			if keyword = normalizePhrase(keyword); keyword != "" {
+				compiled.keywords = append(compiled.keywords, keyword)
+			}
+		}
+		if rule.Pattern != "" {
+			pattern, err := regexp.Compile("(?i)" + rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("trigger %q: invalid pattern: %w", name, err)
+			}
+			compiled.pattern = pattern
+		}
+		if len(compiled.keywords) == 0 && compiled.pattern == nil {
+			return nil, fmt.Errorf("trigger %q has neither keywords nor pattern", name)
+		}
+
+		e.rules = append(e.rules, compiled)
+	}
+
+	return e, nil
+}
+
+// Match возвращает имя сработавшего правила или пустую строку.
+// Срабатывание запоминается, поэтому повторный вызов в пределах cooldown вернет пустую строку.
+func (e *triggerEngine) Match(text string) string {
+	if len(e.rules) == 0 {
+		return ""
+	}
+
+	now := time.Now()
+	normalized := normalizePhrase(text)
+
+	var best *triggerRule
+	for _, rule := range e.rules {
+		if !rule.matches(text, normalized) {
+			continue
+		}
+		if rule.cooldown > 0 && now.Sub(rule.lastFire) < rule.cooldown {
+			continue
+		}
+		if best == nil || rule.priority > best.priority {
+			best = rule
+		}
+	}
+	if best == nil {
+		return ""
+	}
+
+	if now.Sub(e.lastFire) < e.cooldown && best.priority <= e.lastPriority {
+		return ""
+	}
+
+	best.lastFire = now
+	e.lastFire = now
+	e.lastPriority = best.priority
+	return best.name
+}
+
+func (r *triggerRule) matches(text, normalized string) bool {
+	for _, keyword := range r.keywords {
+		if strings.Contains(normalized, keyword) {
+			return true
+		}
+	}
+	return r.pattern != nil && r.pattern.MatchString(text)
+}
+
+// normalizePhrase приводит фразу к нижнему регистру, заменяет ё на е и схлопывает пробелы,
+// чтобы ключевые слова совпадали независимо от того, как их записал транскрибер
+func normalizePhrase(text string) string {
+	text = strings.ToLower(text)
+	text = strings.ReplaceAll(text, "ё", "е")
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"cluely/internal/config"
+)
+
+func testTriggers(t *testing.T, cooldownSec int, rules ...config.TriggerRule) *triggerEngine {
+	t.Helper()
+	e, err := newTriggerEngine(config.AgentConfig{TriggerCooldownSec: cooldownSec, Triggers: rules})
+	if err != nil {
+		t.Fatalf("newTriggerEngine: %v", err)
+	}
+	return e
+}
+
+// elapse сдвигает время срабатываний назад, как будто прошло d
+func (e *triggerEngine) elapse(d time.Duration) {
+	e.lastFire = e.lastFire.Add(-d)
+	for _, rule := range e.rules {
+		rule.lastFire = rule.lastFire.Add(-d)
+	}
+}
+
+func TestTriggerMatching(t *testing.T) {
+	e := testTriggers(t, 0,
+		config.TriggerRule{Name: "logs", Keywords: []string{"Покажи  логи", "  "}},
+		config.TriggerRule{Name: "error", Pattern: `\b5\d\d\b|ошибк`},
+		config.TriggerRule{Keywords: []string{"ещё раз"}},
+	)
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "ну покажи   ЛОГИ пода", want: "logs"},
+		{text: "покажи лог", want: ""},
+		{text: "сервис отдает 503", want: "error"},
+		{text: "Какая ОШИБКА в ответе", want: "error"},
+		{text: "порт 8503", want: ""},
+		{text: "давай еще раз", want: "trigger-3"},
+		{text: "давай ещё раз", want: "trigger-3"},
+		{text: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		// Общий cooldown не должен мешать проверке совпадений
+		e.elapse(time.Hour)
+		if got := e.Match(tt.text); got != tt.want {
+			t.Errorf("Match(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTriggerPriority(t *testing.T) {
+	e := testTriggers(t, 0,
+		config.TriggerRule{Name: "screen", Keywords: []string{"экран"}, Priority: 1},
+		config.TriggerRule{Name: "incident", Keywords: []string{"инцидент"}, Priority: 5},
+		config.TriggerRule{Name: "screen-too", Keywords: []string{"экран"}, Priority: 1},
+	)
+
+	if got := e.Match("инцидент, глянь экран"); got != "incident" {
+		t.Errorf("Match = %q, want the higher priority rule", got)
+	}
+	e.elapse(time.Hour)
+	// При равном приоритете побеждает правило, объявленное раньше
+	if got := e.Match("глянь экран"); got != "screen" {
+		t.Errorf("Match = %q, want the first of equal rules", got)
+	}
+}
+
+func TestTriggerCooldowns(t *testing.T) {
+	e := testTriggers(t, 10,
+		config.TriggerRule{Name: "logs", Keywords: []string{"логи"}, Priority: 1},
+		config.TriggerRule{Name: "metrics", Keywords: []string{"метрики"}, Priority: 1},
+		config.TriggerRule{Name: "incident", Keywords: []string{"инцидент"}, Priority: 5, CooldownSec: 60},
+	)
+
+	steps := []struct {
+		elapse time.Duration
+		text   string
+		want   string
+	}{
+		{text: "покажи логи", want: "logs"},
+		// Общий cooldown действует на правила с тем же и меньшим приоритетом
+		{elapse: 5 * time.Second, text: "покажи логи", want: ""},
+		{text: "покажи метрики", want: ""},
+		// Более приоритетное правило прерывает общий cooldown
+		{text: "у нас инцидент", want: "incident"},
+		// Теперь общий cooldown отсчитывается от него
+		{elapse: 6 * time.Second, text: "покажи метрики", want: ""},
+		{elapse: 5 * time.Second, text: "покажи метрики", want: "metrics"},
+		// Собственный cooldown правила длиннее общего
+		{elapse: 20 * time.Second, text: "снова инцидент", want: ""},
+		// Пока правило ждет свой cooldown, срабатывает следующее подходящее
+		{text: "инцидент, покажи логи", want: "logs"},
+		{elapse: 40 * time.Second, text: "снова инцидент", want: "incident"},
+	}
+
+	for i, step := range steps {
+		e.elapse(step.elapse)
+		if got := e.Match(step.text); got != step.want {
+			t.Errorf("step %d: Match(%q) = %q, want %q", i, step.text, got, step.want)
+		}
+	}
+}
+
+func TestTriggerDefaultCooldown(t *testing.T) {
+	e := testTriggers(t, 0, config.TriggerRule{Name: "logs", Keywords: []string{"логи"}})
+	if e.cooldown != defaultTriggerCooldown {
+		t.Fatalf("cooldown = %v, want %v", e.cooldown, defaultTriggerCooldown)
+	}
+
+	e.Match("логи")
+	e.elapse(defaultTriggerCooldown - time.Second)
+	if got := e.Match("логи"); got != "" {
+		t.Errorf("Match within the default cooldown = %q", got)
+	}
+	e.elapse(2 * time.Second)
+	if got := e.Match("логи"); got != "logs" {
+		t.Errorf("Match after the default cooldown = %q", got)
+	}
+}
+
+func TestTriggerConfigErrors(t *testing.T) {
+	tests := []config.TriggerRule{
+		{Name: "empty"},
+		{Name: "blank keywords", Keywords: []string{" ", ""}},
+		{Name: "broken", Pattern: `(`},
+	}
+	for _, rule := range tests {
+		if _, err := newTriggerEngine(config.AgentConfig{Triggers: []config.TriggerRule{rule}}); err == nil {
+			t.Errorf("rule %q accepted", rule.Name)
+		}
+	}
+
+	e := testTriggers(t, 0)
+	if got := e.Match("покажи логи"); got != "" {
+		t.Errorf("Match without rules = %q", got)
+	}
+}
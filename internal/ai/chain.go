@@ -0,0 +1,288 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultProviderTimeout = 30 * time.Second
+	defaultBreakerFailures = 3
+	defaultBreakerCooldown = 30 * time.Second
+	breakerProbeInterval   = 10 * time.Second
+	breakerProbeTimeout    = 5 * time.Second
+	// providerHealthTimeout ограничивает проверку здоровья при старте, где у контекста нет дедлайна
+	providerHealthTimeout = 10 * time.Second
+)
+
+const (
+	breakerStateClosed   = "closed"
+	breakerStateOpen     = "open"
+	breakerStateHalfOpen = "half-open"
+)
+
+var errNoProvidersAvailable = errors.New("no AI providers available")
+
+// circuitBreaker отключает провайдера после серии ошибок и снова пропускает запросы после cooldown
+type circuitBreaker struct {
+	mu        sync.Mutex
+	state     string
+	failures  int
+	threshold int
+	cooldown  time.Duration
+	openedAt  time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		state:     breakerStateClosed,
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// Allow сообщает, можно ли отправить запрос. По истечении cooldown пропускает один пробный запрос.
+func (b *circuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerStateOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerStateHalfOpen
+		return true
+	case breakerStateHalfOpen:
+		// Пробный запрос уже выполняется
+		return false
+	default:
+		return true
+	}
+}
+
+// Success закрывает breaker и возвращает true, если провайдер был отключен
+func (b *circuitBreaker) Success() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	recovered := b.state != breakerStateClosed
+	b.state = breakerStateClosed
+	b.failures = 0
+	return recovered
+}
+
+// Failure учитывает ошибку и возвращает true, если breaker только что открылся
+func (b *circuitBreaker) Failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == breakerStateHalfOpen || (b.state == breakerStateClosed && b.failures >= b.threshold) {
+		b.state = breakerStateOpen
+		b.openedAt = time.Now()
+		return true
+	}
+	return false
+}
+
+// Release возвращает пробный запрос, прерванный не по вине провайдера (отмена внешнего
+// контекста): breaker снова открыт без учета ошибки, и следующий Allow сразу пропустит
+// новую пробу, ведь cooldown уже прошел. Без этого breaker навсегда остался бы half-open.
+func (b *circuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerStateHalfOpen {
+		b.state = breakerStateOpen
+	}
+}
+
+// Trip сразу открывает breaker, например после неудачной проверки здоровья при старте
+func (b *circuitBreaker) Trip() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = breakerStateOpen
+	b.openedAt = time.Now()
+}
+
+func (b *circuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+type chainEntry struct {
+	name     string
+	provider AIProvider
+	timeout  time.Duration
+	breaker  *circuitBreaker
+}
+
+// ProviderChain опрашивает провайдеров по порядку и переключается на следующий при ошибке.
+// Имя ответившего провайдера записывается в AnalysisOutput.Provider.
+type ProviderChain struct {
+	entries []*chainEntry
+}
+
+func NewProviderChain() *ProviderChain {
+	return &ProviderChain{}
+}
+
+// Add добавляет провайдера в конец цепочки
+func (c *ProviderChain) Add(name string, provider AIProvider, timeout time.Duration, failures int, cooldown time.Duration) {
+	if timeout <= 0 {
+		timeout = defaultProviderTimeout
+	}
+	if failures <= 0 {
+		failures = defaultBreakerFailures
+	}
+	if cooldown <= 0 {
+		cooldown = defaultBreakerCooldown
+	}
+
+	c.entries = append(c.entries, &chainEntry{
+		name:     name,
+		provider: provider,
+		timeout:  timeout,
+		breaker:  newCircuitBreaker(failures, cooldown),
+	})
+}
+
+// Len возвращает число провайдеров в цепочке
+func (c *ProviderChain) Len() int {
+	return len(c.entries)
+}
+
+// Names возвращает имена провайдеров в порядке опроса
+func (c *ProviderChain) Names() []string {
+	names := make([]string, len(c.entries))
+	for i, entry := range c.entries {
+		names[i] = entry.name
+	}
+	return names
+}
+
+func (c *ProviderChain) Analyze(ctx context.Context, input AnalysisInput) (AnalysisOutput, error) {
+	return c.run(ctx, func(ctx context.Context, entry *chainEntry) (AnalysisOutput, error) {
+		return entry.provider.Analyze(ctx, input)
+	})
+}
+
+// AnalyzeStream передает фрагменты от текущего провайдера. Если провайдер упал после того,
+// как начал отдавать текст, следующие провайдеры вызываются без стриминга, чтобы не склеивать
+// в UI ответы разных моделей: полный результат придет в финальном сообщении.
+func (c *ProviderChain) AnalyzeStream(ctx context.Context, input AnalysisInput, onChunk StreamHandler) (AnalysisOutput, error) {
+	streamed := false
+	return c.run(ctx, func(ctx context.Context, entry *chainEntry) (AnalysisOutput, error) {
+		if streamed {
+			return entry.provider.Analyze(ctx, input)
+		}
+		return entry.provider.AnalyzeStream(ctx, input, func(chunk string) {
+			streamed = true
+			if onChunk != nil {
+				onChunk(chunk)
+			}
+		})
+	})
+}
+
+// run выполняет call на первом доступном провайдере с учетом таймаутов и circuit breaker
+func (c *ProviderChain) run(ctx context.Context, call func(context.Context, *chainEntry) (AnalysisOutput, error)) (AnalysisOutput, error) {
+	var errs []string
+
+	for _, entry := range c.entries {
+		if !entry.breaker.Allow() {
+			continue
+		}
+
+		callCtx, cancel := context.WithTimeout(ctx, entry.timeout)
+		output, err := call(callCtx, entry)
+		cancel()
+
+		if err == nil {
+			if entry.breaker.Success() {
+				log.Printf("✅ AI provider '%s' recovered", entry.name)
+			}
+			output.Provider = entry.name
+			return output, nil
+		}
+
+		// Отмена внешнего контекста - не вина провайдера
+		if ctx.Err() != nil {
+			entry.breaker.Release()
+			return AnalysisOutput{}, ctx.Err()
+		}
+
+		errs = append(errs, fmt.Sprintf("%s: %v", entry.name, err))
+		if entry.breaker.Failure() {
+			log.Printf("⚠️  AI provider '%s' disabled by circuit breaker: %v", entry.name, err)
+		} else {
+			log.Printf("⚠️  AI provider '%s' failed, trying next: %v", entry.name, err)
+		}
+	}
+
+	if len(errs) == 0 {
+		return AnalysisOutput{}, errNoProvidersAvailable
+	}
+	return AnalysisOutput{}, fmt.Errorf("all AI providers failed: %s", strings.Join(errs, "; "))
+}
+
+// Health проверяет провайдеров по порядку и возвращает nil, если хотя бы один здоров.
+// Нездоровые провайдеры сразу отключаются, чтобы запросы шли к следующим в цепочке.
+func (c *ProviderChain) Health(ctx context.Context) error {
+	var errs []string
+	healthy := false
+
+	for _, entry := range c.entries {
+		if err := entry.provider.Health(ctx); err != nil {
+			entry.breaker.Trip()
+			log.Printf("⚠️  AI provider '%s' unhealthy, disabled until it recovers: %v", entry.name, err)
+			errs = append(errs, fmt.Sprintf("%s: %v", entry.name, err))
+			continue
+		}
+		entry.breaker.Success()
+		healthy = true
+	}
+
+	if healthy {
+		return nil
+	}
+	if len(errs) == 0 {
+		return errNoProvidersAvailable
+	}
+	return fmt.Errorf("no healthy AI providers: %s", strings.Join(errs, "; "))
+}
+
+// Monitor периодически проверяет здоровье отключенных провайдеров и возвращает их в цепочку
+func (c *ProviderChain) Monitor(ctx context.Context) {
+	ticker := time.NewTicker(breakerProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, entry := range c.entries {
+				if entry.breaker.State() != breakerStateOpen {
+					continue
+				}
+
+				probeCtx, cancel := context.WithTimeout(ctx, breakerProbeTimeout)
+				err := entry.provider.Health(probeCtx)
+				cancel()
+
+				if err == nil && entry.breaker.Success() {
+					log.Printf("✅ AI provider '%s' is healthy again", entry.name)
+				}
+			}
+		}
+	}
+}
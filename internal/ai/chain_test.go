@@ -0,0 +1,266 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubProvider - провайдер для тестов цепочки: возвращает заданную ошибку или подсказку
+// и считает вызовы. С block ждет отмены контекста.
+type stubProvider struct {
+	mu     sync.Mutex
+	hint   string
+	err    error
+	chunks []string
+	block  bool
+	calls  int
+	stream int
+}
+
+func (p *stubProvider) setErr(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+func (p *stubProvider) counts() (calls, stream int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls, p.stream
+}
+
+func (p *stubProvider) Analyze(ctx context.Context, input AnalysisInput) (AnalysisOutput, error) {
+	p.mu.Lock()
+	p.calls++
+	hint, err, block := p.hint, p.err, p.block
+	p.mu.Unlock()
+
+	if block {
+		<-ctx.Done()
+		return AnalysisOutput{}, ctx.Err()
+	}
+	if err != nil {
+		return AnalysisOutput{}, err
+	}
+	return AnalysisOutput{Hint: hint, Provider: "ignored"}, nil
+}
+
+func (p *stubProvider) AnalyzeStream(ctx context.Context, input AnalysisInput, onChunk StreamHandler) (AnalysisOutput, error) {
+	p.mu.Lock()
+	p.stream++
+	chunks := p.chunks
+	p.mu.Unlock()
+
+	for _, chunk := range chunks {
+		onChunk(chunk)
+	}
+	output, err := p.Analyze(ctx, input)
+	p.mu.Lock()
+	p.calls--
+	p.mu.Unlock()
+	return output, err
+}
+
+func (p *stubProvider) Health(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+func TestChainFailoverOrder(t *testing.T) {
+	first := &stubProvider{err: errors.New("connection refused")}
+	second := &stubProvider{hint: "from second"}
+	third := &stubProvider{hint: "from third"}
+
+	chain := NewProviderChain()
+	chain.Add("ollama", first, time.Second, 3, time.Minute)
+	chain.Add("cloud", second, time.Second, 3, time.Minute)
+	chain.Add("spare", third, time.Second, 3, time.Minute)
+
+	output, err := chain.Analyze(context.Background(), AnalysisInput{})
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if output.Hint != "from second" || output.Provider != "cloud" {
+		t.Errorf("output = %+v, want the hint from cloud", output)
+	}
+	for i, want := range []int{1, 1, 0} {
+		provider := []*stubProvider{first, second, third}[i]
+		if calls, _ := provider.counts(); calls != want {
+			t.Errorf("provider %s called %d times, want %d", chain.Names()[i], calls, want)
+		}
+	}
+}
+
+func TestChainAllProvidersFail(t *testing.T) {
+	chain := NewProviderChain()
+	chain.Add("ollama", &stubProvider{err: errors.New("refused")}, time.Second, 3, time.Minute)
+	chain.Add("cloud", &stubProvider{err: errors.New("429")}, time.Second, 3, time.Minute)
+
+	_, err := chain.Analyze(context.Background(), AnalysisInput{})
+	if err == nil || !strings.Contains(err.Error(), "ollama: refused") || !strings.Contains(err.Error(), "cloud: 429") {
+		t.Errorf("error = %v, want both provider errors", err)
+	}
+}
+
+func TestChainProviderTimeout(t *testing.T) {
+	slow := &stubProvider{block: true}
+	chain := NewProviderChain()
+	chain.Add("slow", slow, 20*time.Millisecond, 3, time.Minute)
+	chain.Add("fast", &stubProvider{hint: "ok"}, time.Second, 3, time.Minute)
+
+	output, err := chain.Analyze(context.Background(), AnalysisInput{})
+	if err != nil || output.Provider != "fast" {
+		t.Fatalf("output = %+v, err = %v; want fallback to fast", output, err)
+	}
+	if state := chain.entries[0].breaker.State(); state != breakerStateClosed {
+		t.Errorf("breaker after one timeout = %s, want closed", state)
+	}
+	if chain.entries[0].breaker.failures != 1 {
+		t.Errorf("failures = %d, want the timeout counted", chain.entries[0].breaker.failures)
+	}
+}
+
+func TestChainBreakerRecovery(t *testing.T) {
+	flaky := &stubProvider{hint: "primary", err: errors.New("refused")}
+	backup := &stubProvider{hint: "backup"}
+
+	chain := NewProviderChain()
+	chain.Add("primary", flaky, time.Second, 2, 30*time.Millisecond)
+	chain.Add("backup", backup, time.Second, 2, time.Minute)
+	breaker := chain.entries[0].breaker
+
+	// Две ошибки подряд открывают breaker
+	for i := 0; i < 2; i++ {
+		chain.Analyze(context.Background(), AnalysisInput{})
+	}
+	if state := breaker.State(); state != breakerStateOpen {
+		t.Fatalf("state after threshold = %s, want open", state)
+	}
+
+	// Пока идет cooldown, провайдер пропускается
+	flaky.setErr(nil)
+	output, _ := chain.Analyze(context.Background(), AnalysisInput{})
+	if calls, _ := flaky.counts(); calls != 2 || output.Provider != "backup" {
+		t.Errorf("open breaker: primary called %d times, answered by %s", calls, output.Provider)
+	}
+
+	// После cooldown пробный запрос закрывает breaker
+	time.Sleep(40 * time.Millisecond)
+	output, err := chain.Analyze(context.Background(), AnalysisInput{})
+	if err != nil || output.Provider != "primary" {
+		t.Fatalf("after cooldown: output %+v, err %v; want primary", output, err)
+	}
+	if state := breaker.State(); state != breakerStateClosed {
+		t.Errorf("state after successful trial = %s, want closed", state)
+	}
+}
+
+func TestBreakerFailedTrialReopens(t *testing.T) {
+	b := newCircuitBreaker(3, 20*time.Millisecond)
+	b.Trip()
+	if b.Allow() {
+		t.Fatal("Allow during cooldown")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if !b.Allow() {
+		t.Fatal("no trial after cooldown")
+	}
+	if b.State() != breakerStateHalfOpen || b.Allow() {
+		t.Fatalf("state = %s; a second trial was allowed", b.State())
+	}
+	if !b.Failure() || b.State() != breakerStateOpen {
+		t.Errorf("failed trial left breaker %s, want open", b.State())
+	}
+	if b.Allow() {
+		t.Error("Allow right after a failed trial, want a new cooldown")
+	}
+}
+
+func TestChainCancelledTrialReleasesBreaker(t *testing.T) {
+	primary := &stubProvider{block: true}
+	chain := NewProviderChain()
+	chain.Add("primary", primary, time.Second, 3, 10*time.Millisecond)
+	chain.Add("backup", &stubProvider{hint: "backup"}, time.Second, 3, time.Minute)
+	breaker := chain.entries[0].breaker
+
+	breaker.Trip()
+	time.Sleep(20 * time.Millisecond)
+
+	// Пробный запрос прерывается отменой внешнего контекста
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := chain.Analyze(ctx, AnalysisInput{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Analyze = %v, want context.Canceled", err)
+	}
+
+	if state := breaker.State(); state != breakerStateOpen {
+		t.Fatalf("state after cancelled trial = %s, want open", state)
+	}
+	if breaker.failures != 0 {
+		t.Errorf("cancelled trial counted as %d failures", breaker.failures)
+	}
+
+	// Следующий запрос снова пробует провайдера и возвращает его в цепочку
+	primary.mu.Lock()
+	primary.block = false
+	primary.hint = "primary"
+	primary.mu.Unlock()
+	output, err := chain.Analyze(context.Background(), AnalysisInput{})
+	if err != nil || output.Provider != "primary" {
+		t.Fatalf("output = %+v, err = %v; want primary back", output, err)
+	}
+	if state := breaker.State(); state != breakerStateClosed {
+		t.Errorf("state = %s, want closed", state)
+	}
+}
+
+func TestChainStreamFallsBackWithoutStreaming(t *testing.T) {
+	primary := &stubProvider{chunks: []string{"Pods ", "restart"}, err: errors.New("stream broken")}
+	backup := &stubProvider{hint: "full answer"}
+
+	chain := NewProviderChain()
+	chain.Add("primary", primary, time.Second, 3, time.Minute)
+	chain.Add("backup", backup, time.Second, 3, time.Minute)
+
+	var chunks []string
+	output, err := chain.AnalyzeStream(context.Background(), AnalysisInput{}, func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatalf("AnalyzeStream: %v", err)
+	}
+	if output.Hint != "full answer" || output.Provider != "backup" {
+		t.Errorf("output = %+v", output)
+	}
+	if strings.Join(chunks, "") != "Pods restart" {
+		t.Errorf("chunks = %q, want only the primary's text", chunks)
+	}
+	if calls, stream := backup.counts(); calls != 1 || stream != 0 {
+		t.Errorf("backup: %d Analyze, %d AnalyzeStream; want a non-streaming call", calls, stream)
+	}
+}
+
+func TestChainHealthTripsUnhealthyProviders(t *testing.T) {
+	chain := NewProviderChain()
+	chain.Add("ollama", &stubProvider{err: errors.New("refused")}, time.Second, 3, time.Minute)
+	chain.Add("cloud", &stubProvider{}, time.Second, 3, time.Minute)
+
+	if err := chain.Health(context.Background()); err != nil {
+		t.Fatalf("Health = %v with one healthy provider", err)
+	}
+	if state := chain.entries[0].breaker.State(); state != breakerStateOpen {
+		t.Errorf("unhealthy provider state = %s, want open", state)
+	}
+	if state := chain.entries[1].breaker.State(); state != breakerStateClosed {
+		t.Errorf("healthy provider state = %s, want closed", state)
+	}
+}
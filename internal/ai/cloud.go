@@ -0,0 +1,241 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+const openAIBaseURL = "https://api.openai.com/v1"
+
+// CloudProvider работает с любым сервером, совместимым с OpenAI chat completions API:
+// OpenAI, а также локальными llama.cpp и vLLM.
+//
+// Согласно NFR-1 данные могут покидать машину только с явного согласия пользователя,
+// поэтому провайдер не создается без флага consent, а размер каждого исходящего запроса логируется.
+type CloudProvider struct {
+	baseURL string
+	apiKey  string
+	model   string
+	prompts *PromptStore
+	client  *http.Client
+}
+
+func NewCloudProvider(baseURL, apiKey, model string, consent bool, prompts *PromptStore) (*CloudProvider, error) {
+	if !consent {
+		return nil, errors.New("cloud AI provider requires explicit consent: set ai.cloud_consent = true")
+	}
+	if baseURL == "" {
+		baseURL = openAIBaseURL
+	}
+	if model == "" {
+		model = "gpt-4"
+	}
+
+	log.Printf("⚠️  Cloud AI enabled with explicit consent: analysis data will be sent to %s", baseURL)
+
+	return &CloudProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		prompts: prompts,
+		// Без Timeout, как у Ollama: потоковый ответ ограничивает контекст цепочки
+		client: &http.Client{},
+	}, nil
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatResponseFormat struct {
+	Type string `json:"type"`
+}
+
+type chatRequest struct {
+	Model          string              `json:"model"`
+	Messages       []chatMessage       `json:"messages"`
+	Stream         bool                `json:"stream"`
+	ResponseFormat *chatResponseFormat `json:"response_format,omitempty"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+		Delta   chatMessage `json:"delta"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func (c *CloudProvider) Analyze(ctx context.Context, input AnalysisInput) (AnalysisOutput, error) {
+	prompt, err := c.buildPrompt(input)
+	if err != nil {
+		return AnalysisOutput{}, err
+	}
+
+	raw, err := c.complete(ctx, prompt)
+	if err != nil {
+		return AnalysisOutput{}, err
+	}
+
+	return c.parseOutput(ctx, raw), nil
+}
+
+// AnalyzeStream читает SSE поток chat completions и передает в onChunk новые фрагменты поля "hint"
+func (c *CloudProvider) AnalyzeStream(ctx context.Context, input AnalysisInput, onChunk StreamHandler) (AnalysisOutput, error) {
+	prompt, err := c.buildPrompt(input)
+	if err != nil {
+		return AnalysisOutput{}, err
+	}
+
+	resp, err := c.chat(ctx, prompt, true)
+	if err != nil {
+		return AnalysisOutput{}, err
+	}
+	defer resp.Body.Close()
+
+	stream := &hintStream{onChunk: onChunk}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			return c.parseOutput(ctx, stream.String()), nil
+		}
+
+		var chunk chatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return AnalysisOutput{}, fmt.Errorf("cloud stream decode failed: %w", err)
+		}
+		if chunk.Error != nil {
+			return AnalysisOutput{}, fmt.Errorf("cloud stream error: %s", chunk.Error.Message)
+		}
+		if len(chunk.Choices) > 0 {
+			stream.Write(chunk.Choices[0].Delta.Content)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return AnalysisOutput{}, fmt.Errorf("cloud stream read failed: %w", err)
+	}
+
+	return AnalysisOutput{}, fmt.Errorf("cloud stream ended without [DONE] marker")
+}
+
+func (c *CloudProvider) Health(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, providerHealthTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/models", nil)
+	if err != nil {
+		return err
+	}
+	c.authorize(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cloud health check failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("cloud provider not healthy: status %d", resp.StatusCode)
+	}
+
+	log.Printf("✅ Cloud AI is healthy: %s (model: %s)", c.baseURL, c.model)
+	return nil
+}
+
+// parseOutput разбирает JSON ответ модели с одной попыткой исправления
+func (c *CloudProvider) parseOutput(ctx context.Context, raw string) AnalysisOutput {
+	return parseWithRepair(ctx, "Cloud AI", c.prompts.Language(), raw, c.complete)
+}
+
+func (c *CloudProvider) buildPrompt(input AnalysisInput) (string, error) {
+	prompt, err := c.prompts.Render(input)
+	if err != nil {
+		return "", err
+	}
+
+	return prompt + jsonInstruction(c.prompts.Language()), nil
+}
+
+// complete выполняет непотоковый запрос и возвращает текст ответа модели
+func (c *CloudProvider) complete(ctx context.Context, prompt string) (string, error) {
+	resp, err := c.chat(ctx, prompt, false)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var chatResp chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return "", err
+	}
+	if chatResp.Error != nil {
+		return "", fmt.Errorf("cloud error: %s", chatResp.Error.Message)
+	}
+	if len(chatResp.Choices) == 0 {
+		return "", errors.New("cloud response has no choices")
+	}
+
+	return chatResp.Choices[0].Message.Content, nil
+}
+
+// chat отправляет запрос в /chat/completions и возвращает ответ с непрочитанным телом
+func (c *CloudProvider) chat(ctx context.Context, prompt string, stream bool) (*http.Response, error) {
+	reqBody := chatRequest{
+		Model:          c.model,
+		Messages:       []chatMessage{{Role: "user", Content: prompt}},
+		Stream:         stream,
+		ResponseFormat: &chatResponseFormat{Type: "json_object"},
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	url := c.baseURL + "/chat/completions"
+	log.Printf("☁️  Cloud AI request: %d bytes -> %s", len(jsonData), url)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.authorize(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cloud request failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("cloud returned status %d", resp.StatusCode)
+	}
+
+	return resp, nil
+}
+
+// authorize добавляет API ключ; локальные серверы обычно работают без него
+func (c *CloudProvider) authorize(req *http.Request) {
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+}
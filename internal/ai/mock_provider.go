@@ -3,6 +3,7 @@ package ai
 import (
 	"context"
 	"log"
+	"strings"
 	"time"
 )
 
@@ -23,100 +24,16 @@ func (m *MockAIProvider) Analyze(ctx context.Context, input AnalysisInput) (Anal
 	var tasks []string
 	var warnings []string
 
-	if input.Type == "audio" {
-		// Анализируем аудиотранскрипцию
-		if contains(input.TranscriptText, "CPU") {
-			hint = "⚠️ Критическая нагрузка CPU обнаружена! Рекомендуй проверить top -H и recent deploys."
-			tasks = []string{
-				"Проверить использование процессов: top -H",
-				"Просмотреть последние деплои",
-				"Проверить memory leaks",
-			}
-			warnings = []string{"Возможен DDoS или infinite loop"}
-		} else if contains(input.TranscriptText, "Memory leak") {
-			hint = "🔴 Memory leak обнаружен! Выполни откат к предыдущему коммиту и проверь heap dump."
-			tasks = []string{
-				"Выполнить git rollback",
-				"Проверить heap dump",
-				"Запустить memory profiler",
-			}
-			warnings = []string{"Откат может потребовать перезагрузку", "Проверь dependencies перед откатом"}
-		} else if contains(input.TranscriptText, "откатиться") {
-			hint = "🔄 Перед откатом убедитесь, что завершены все ongoing transactions."
-			tasks = []string{
-				"Проверить active transactions",
-				"Создать backup текущего состояния",
-				"Выполнить rollback",
-			}
-			warnings = []string{"Может потребоваться downtime", "Уведомить stakeholders"}
-		} else if contains(input.TranscriptText, "Database") {
-			hint = "💾 Database issue! Проверь connection pool и query performance."
-			tasks = []string{
-				"Проверить connection pool: SHOW PROCESSLIST",
-				"Анализировать slow queries",
-				"Проверить disk space",
-			}
-			warnings = []string{"Возможна блокировка", "Проверь репликацию"}
-		} else if contains(input.TranscriptText, "API") {
-			hint = "🌐 API issue! Проверь endpoints availability и load balancer."
-			tasks = []string{
-				"Проверить API health endpoints",
-				"Посмотреть логи load balancer'а",
-				"Проверить rate limits",
-			}
-			warnings = []string{"Возможен rate limiting", "Проверь DNS resolution"}
-		} else {
-			hint = "ℹ️ Подсказка: используй стандартные инструменты диагностики (top, netstat, curl)."
-			tasks = []string{
-				"Собрать метрики системы",
-				"Проверить логи приложения",
-				"Провести базовую диагностику",
-			}
-			warnings = []string{"Собери достаточно контекста перед действиями"}
-		}
-	} else if input.Type == "vision" {
-		// Анализируем текст из скриншотов
-		if contains(input.OCRText, "ERROR") || contains(input.OCRText, "error") {
-			hint = "🔴 Обнаружена ERROR в логах! Определи компонент и проверь stack trace."
-			tasks = []string{
-				"Найти точный компонент ошибки",
-				"Посмотреть полный stack trace",
-				"Проверить related errors",
-			}
-			warnings = []string{"Может быть cascade failure"}
-		} else if contains(input.OCRText, "95%") || contains(input.OCRText, "CPU") {
-			hint = "🔥 Критическая метрика обнаружена: CPU/Memory на уровне 95%+. Срочно проверь top!"
-			tasks = []string{
-				"Выполнить: top -H | head -20",
-				"Посмотреть процессы с максимальным usage",
-				"Проверить network I/O",
-			}
-			warnings = []string{"Система может скоро упасть", "Приготовься к аварийному откату"}
-		} else if contains(input.OCRText, "CrashLoopBackOff") {
-			hint = "💥 Pod находится в CrashLoopBackOff! Проверь последние логи и events."
-			tasks = []string{
-				"Просмотреть pod events: kubectl describe pod",
-				"Посмотреть логи: kubectl logs",
-				"Проверить resource limits",
-			}
-			warnings = []string{"Возможна нехватка ресурсов", "Проверь health checks"}
-		} else if contains(input.OCRText, "503") || contains(input.OCRText, "Unavailable") {
-			hint = "⚠️ Service Unavailable (503). Проверь backend status и load balancer configuration."
-			tasks = []string{
-				"Проверить health endpoints всех backend'ов",
-				"Посмотреть логи load balancer'а",
-				"Проверить network connectivity",
-			}
-			warnings = []string{"Возможен cascading failure"}
-		} else {
-			hint = "📊 Метрики собраны. Проанализируй тренды и сравни с baseline'ом."
-			tasks = []string{
-				"Определить anomalies",
-				"Сравнить с историческими данными",
-				"Проверить correlation с recent changes",
-			}
-			warnings = []string{}
-		}
+	switch input.Type {
+	case "audio":
+		hint, tasks, warnings = analyzeTranscript(input.TranscriptText)
+	case "vision":
+		hint, tasks, warnings = analyzeScreen(input.OCRText)
+	case "combined":
+		hint, tasks, warnings = analyzeCombined(input.TranscriptText, input.OCRText)
+	case "question":
+		hint, tasks, warnings = analyzeTranscript(input.Question)
+		hint = "💬 " + hint
 	}
 
 	log.Printf("🤖 Mock AI #%d (type=%s): %s", m.counter, input.Type, hint)
@@ -135,11 +52,171 @@ func (m *MockAIProvider) Analyze(ctx context.Context, input AnalysisInput) (Anal
 	}
 }
 
+// AnalyzeStream отдает подсказку по словам, чтобы имитировать потоковую генерацию
+func (m *MockAIProvider) AnalyzeStream(ctx context.Context, input AnalysisInput, onChunk StreamHandler) (AnalysisOutput, error) {
+	output, err := m.Analyze(ctx, input)
+	if err != nil {
+		return output, err
+	}
+
+	if onChunk != nil {
+		words := strings.SplitAfter(output.Hint, " ")
+		for _, word := range words {
+			select {
+			case <-time.After(50 * time.Millisecond):
+				onChunk(word)
+			case <-ctx.Done():
+				return AnalysisOutput{}, ctx.Err()
+			}
+		}
+	}
+
+	return output, nil
+}
+
 func (m *MockAIProvider) Health(ctx context.Context) error {
 	log.Println("🤖 Mock AI Provider is healthy")
 	return nil
 }
 
+// analyzeTranscript подбирает подсказку по фразе из аудиотранскрипции
+func analyzeTranscript(text string) (hint string, tasks []string, warnings []string) {
+	if contains(text, "CPU") {
+		hint = "⚠️ Критическая нагрузка CPU обнаружена! Рекомендуй проверить top -H и recent deploys."
+		tasks = []string{
+			"Проверить использование процессов: top -H",
+			"Просмотреть последние деплои",
+			"Проверить memory leaks",
+		}
+		warnings = []string{"Возможен DDoS или infinite loop"}
+	} else if contains(text, "Memory leak") {
+		hint = "🔴 Memory leak обнаружен! Выполни откат к предыдущему коммиту и проверь heap dump."
+		tasks = []string{
+			"Выполнить git rollback",
+			"Проверить heap dump",
+			"Запустить memory profiler",
+		}
+		warnings = []string{"Откат может потребовать перезагрузку", "Проверь dependencies перед откатом"}
+	} else if contains(text, "откатиться") {
+		hint = "🔄 Перед откатом убедитесь, что завершены все ongoing transactions."
+		tasks = []string{
+			"Проверить active transactions",
+			"Создать backup текущего состояния",
+			"Выполнить rollback",
+		}
+		warnings = []string{"Может потребоваться downtime", "Уведомить stakeholders"}
+	} else if contains(text, "Database") {
+		hint = "💾 Database issue! Проверь connection pool и query performance."
+		tasks = []string{
+			"Проверить connection pool: SHOW PROCESSLIST",
+			"Анализировать slow queries",
+			"Проверить disk space",
+		}
+		warnings = []string{"Возможна блокировка", "Проверь репликацию"}
+	} else if contains(text, "API") {
+		hint = "🌐 API issue! Проверь endpoints availability и load balancer."
+		tasks = []string{
+			"Проверить API health endpoints",
+			"Посмотреть логи load balancer'а",
+			"Проверить rate limits",
+		}
+		warnings = []string{"Возможен rate limiting", "Проверь DNS resolution"}
+	} else {
+		hint = "ℹ️ Подсказка: используй стандартные инструменты диагностики (top, netstat, curl)."
+		tasks = []string{
+			"Собрать метрики системы",
+			"Проверить логи приложения",
+			"Провести базовую диагностику",
+		}
+		warnings = []string{"Собери достаточно контекста перед действиями"}
+	}
+	return hint, tasks, warnings
+}
+
+// analyzeScreen подбирает подсказку по тексту со скриншота
+func analyzeScreen(text string) (hint string, tasks []string, warnings []string) {
+	if contains(text, "ERROR") || contains(text, "error") {
+		hint = "🔴 Обнаружена ERROR в логах! Определи компонент и проверь stack trace."
+		tasks = []string{
+			"Найти точный компонент ошибки",
+			"Посмотреть полный stack trace",
+			"Проверить related errors",
+		}
+		warnings = []string{"Может быть cascade failure"}
+	} else if contains(text, "95%") || contains(text, "CPU") {
+		hint = "🔥 Критическая метрика обнаружена: CPU/Memory на уровне 95%+. Срочно проверь top!"
+		tasks = []string{
+			"Выполнить: top -H | head -20",
+			"Посмотреть процессы с максимальным usage",
+			"Проверить network I/O",
+		}
+		warnings = []string{"Система может скоро упасть", "Приготовься к аварийному откату"}
+	} else if contains(text, "CrashLoopBackOff") {
+		hint = "💥 Pod находится в CrashLoopBackOff! Проверь последние логи и events."
+		tasks = []string{
+			"Просмотреть pod events: kubectl describe pod",
+			"Посмотреть логи: kubectl logs",
+			"Проверить resource limits",
+		}
+		warnings = []string{"Возможна нехватка ресурсов", "Проверь health checks"}
+	} else if contains(text, "503") || contains(text, "Unavailable") {
+		hint = "⚠️ Service Unavailable (503). Проверь backend status и load balancer configuration."
+		tasks = []string{
+			"Проверить health endpoints всех backend'ов",
+			"Посмотреть логи load balancer'а",
+			"Проверить network connectivity",
+		}
+		warnings = []string{"Возможен cascading failure"}
+	} else {
+		hint = "📊 Метрики собраны. Проанализируй тренды и сравни с baseline'ом."
+		tasks = []string{
+			"Определить anomalies",
+			"Сравнить с историческими данными",
+			"Проверить correlation с recent changes",
+		}
+		warnings = []string{}
+	}
+	return hint, tasks, warnings
+}
+
+// analyzeCombined сопоставляет сказанное с тем, что видно на экране
+func analyzeCombined(transcript, ocrText string) (hint string, tasks []string, warnings []string) {
+	switch {
+	case contains(transcript, "CPU") && (contains(ocrText, "CPU") || contains(ocrText, "95%")):
+		hint = "🔥 Метрики на экране подтверждают жалобу на CPU. Найди процесс-виновник через top -H и сверь время всплеска с последним деплоем."
+		tasks = []string{
+			"Выполнить: top -H | head -20",
+			"Сопоставить начало нагрузки с временем деплоя",
+			"Подготовить откат, если всплеск совпадает с релизом",
+		}
+		warnings = []string{"Проблема подтверждена двумя источниками - не откладывай действия"}
+	case (contains(transcript, "Memory leak") || contains(transcript, "откатиться")) && contains(ocrText, "CrashLoopBackOff"):
+		hint = "💥 Pod в CrashLoopBackOff совпадает с обсуждаемой утечкой памяти. Проверь OOMKilled в events и готовь откат."
+		tasks = []string{
+			"Проверить причину рестартов: kubectl describe pod",
+			"Убедиться в OOMKilled в last state",
+			"Выполнить rollback деплоймента",
+		}
+		warnings = []string{"Повышение memory limits лишь отсрочит падение", "Сохрани heap dump до отката"}
+	case contains(transcript, "Database") && (contains(ocrText, "ERROR") || contains(ocrText, "error")):
+		hint = "💾 Ошибки на экране подтверждают проблему с базой. Проверь исчерпание connection pool."
+		tasks = []string{
+			"Проверить connection pool: SHOW PROCESSLIST",
+			"Найти сервис, удерживающий соединения",
+			"Проверить network между приложением и БД",
+		}
+		warnings = []string{"Перезапуск приложений может усилить шторм подключений"}
+	default:
+		// Явной связи нет - объединяем выводы по речи и по экрану
+		screenHint, screenTasks, screenWarnings := analyzeScreen(ocrText)
+		_, speechTasks, speechWarnings := analyzeTranscript(transcript)
+		hint = "🔗 " + screenHint
+		tasks = append(screenTasks, speechTasks...)
+		warnings = append(screenWarnings, speechWarnings...)
+	}
+	return hint, tasks, warnings
+}
+
 func contains(s, substr string) bool {
 	for i := 0; i < len(s)-len(substr)+1; i++ {
 		if s[i:i+len(substr)] == substr {
@@ -2,7 +2,11 @@ package ai
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"cluely/internal/config"
 )
@@ -11,6 +15,7 @@ import (
 type Module struct {
 	cfg      config.AIConfig
 	provider AIProvider
+	prompts  *PromptStore
 }
 
 func NewModule(cfg config.AIConfig) *Module {
@@ -20,30 +25,85 @@ func NewModule(cfg config.AIConfig) *Module {
 }
 
 func (m *Module) Initialize(ctx context.Context) error {
-	// Создаем AI провайдера на основе конфига
-	var provider AIProvider
-	var err error
+	// Собираем цепочку провайдеров на основе конфига. Без ai.providers используется один ai.provider.
+	names := m.cfg.Providers
+	if len(names) == 0 {
+		names = []string{m.cfg.Provider}
+	}
 
-	switch m.cfg.Provider {
-	case "ollama":
-		provider = NewOllamaProvider(m.cfg.OllamaURL, m.cfg.Model)
-	case "mock":
-		provider = NewMockAIProvider()
-	default:
-		provider = NewMockAIProvider()
-		log.Printf("⚠️  Unknown AI provider '%s', using mock", m.cfg.Provider)
+	chain := NewProviderChain()
+	var errs []error
+	for _, name := range names {
+		provider, err := m.newProvider(ctx, name)
+		if err != nil {
+			log.Printf("⚠️  AI provider '%s' skipped: %v", name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+
+		timeout := time.Duration(m.cfg.ProviderTimeoutMs[name]) * time.Millisecond
+		cooldown := time.Duration(m.cfg.BreakerCooldownSec) * time.Second
+		chain.Add(name, provider, timeout, m.cfg.BreakerFailures, cooldown)
+	}
+
+	if chain.Len() == 0 {
+		return fmt.Errorf("no AI providers could be initialized: %w", errors.Join(errs...))
 	}
 
-	m.provider = provider
+	m.provider = chain
+	go chain.Monitor(ctx)
 
-	// Проверяем здоровье провайдера
+	// Проверяем здоровье провайдеров; нездоровые отключаются до восстановления
 	if err := m.provider.Health(ctx); err != nil {
 		log.Printf("⚠️  AI provider health check failed: %v (will try to continue)", err)
 		// Не возвращаем ошибку, чтобы система работала даже если AI недоступен
 	}
 
-	log.Printf("✅ AI Module initialized (provider: %s, model: %s)", m.cfg.Provider, m.cfg.Model)
-	return err
+	log.Printf("✅ AI Module initialized (providers: %s, model: %s)", strings.Join(chain.Names(), " -> "), m.cfg.Model)
+	return nil
+}
+
+// newProvider создает AI провайдера по имени из конфига
+func (m *Module) newProvider(ctx context.Context, name string) (AIProvider, error) {
+	switch name {
+	case "ollama":
+		prompts, err := m.loadPrompts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return NewOllamaProvider(m.cfg.OllamaURL, m.cfg.Model, prompts), nil
+	case "cloud":
+		if m.cfg.CloudURL == "" && m.cfg.CloudProvider != "" && m.cfg.CloudProvider != "openai" {
+			return nil, fmt.Errorf("ai.cloud_url is required for cloud provider '%s'", m.cfg.CloudProvider)
+		}
+		prompts, err := m.loadPrompts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return NewCloudProvider(m.cfg.CloudURL, m.cfg.CloudAPIKey, m.cfg.CloudModel, m.cfg.CloudConsent, prompts)
+	case "mock":
+		return NewMockAIProvider(), nil
+	default:
+		log.Printf("⚠️  Unknown AI provider '%s', using mock", name)
+		return NewMockAIProvider(), nil
+	}
+}
+
+// loadPrompts загружает шаблоны промптов и запускает их отслеживание.
+// Шаблоны общие для всех провайдеров цепочки.
+func (m *Module) loadPrompts(ctx context.Context) (*PromptStore, error) {
+	if m.prompts != nil {
+		return m.prompts, nil
+	}
+
+	prompts, err := NewPromptStore(m.cfg.PromptDir, m.cfg.Language)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load prompt templates: %w", err)
+	}
+	go prompts.Watch(ctx)
+
+	m.prompts = prompts
+	return prompts, nil
 }
 
 func (m *Module) Analyze(ctx context.Context, input AnalysisInput) (AnalysisOutput, error) {
@@ -58,6 +118,19 @@ func (m *Module) Analyze(ctx context.Context, input AnalysisInput) (AnalysisOutp
 	return m.provider.Analyze(ctx, input)
 }
 
+// AnalyzeStream анализирует ввод, передавая фрагменты подсказки в onChunk по мере генерации
+func (m *Module) AnalyzeStream(ctx context.Context, input AnalysisInput, onChunk StreamHandler) (AnalysisOutput, error) {
+	if m.provider == nil {
+		output, err := m.Analyze(ctx, input)
+		if err == nil && onChunk != nil {
+			onChunk(output.Hint)
+		}
+		return output, err
+	}
+
+	return m.provider.AnalyzeStream(ctx, input, onChunk)
+}
+
 func (m *Module) Health(ctx context.Context) error {
 	if m.provider == nil {
 		// Инициализируем провайдера если еще не инициализирован
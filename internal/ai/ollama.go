@@ -1,22 +1,23 @@
 package ai
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
-	"time"
 )
 
 type OllamaProvider struct {
 	baseURL string
 	model   string
+	prompts *PromptStore
 	client  *http.Client
 }
 
-func NewOllamaProvider(url, model string) *OllamaProvider {
+func NewOllamaProvider(url, model string, prompts *PromptStore) *OllamaProvider {
 	if url == "" {
 		url = "http://localhost:11434"
 	}
@@ -27,87 +28,156 @@ func NewOllamaProvider(url, model string) *OllamaProvider {
 	return &OllamaProvider{
 		baseURL: url,
 		model:   model,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		prompts: prompts,
+		// Без Timeout: он ограничивает и чтение тела, то есть обрывал бы потоковую генерацию.
+		// Запросы ограничивает контекст - таймаут провайдера в цепочке (provider_timeout_ms).
+		client: &http.Client{},
 	}
 }
 
 type ollamaRequest struct {
-	Model  string `json:"model"`
-	Prompt string `json:"prompt"`
-	Stream bool   `json:"stream"`
+	Model  string          `json:"model"`
+	Prompt string          `json:"prompt"`
+	Stream bool            `json:"stream"`
+	Format json.RawMessage `json:"format,omitempty"`
 }
 
 type ollamaResponse struct {
 	Response string `json:"response"`
 	Done     bool   `json:"done"`
+	Error    string `json:"error,omitempty"`
 }
 
 func (o *OllamaProvider) Analyze(ctx context.Context, input AnalysisInput) (AnalysisOutput, error) {
-	prompt := o.buildPrompt(input)
-
-	reqBody := ollamaRequest{
-		Model:  o.model,
-		Prompt: prompt,
-		Stream: false,
+	prompt, err := o.buildPrompt(input)
+	if err != nil {
+		return AnalysisOutput{}, err
 	}
 
-	jsonData, err := json.Marshal(reqBody)
+	raw, err := o.complete(ctx, prompt)
 	if err != nil {
 		return AnalysisOutput{}, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/api/generate", bytes.NewBuffer(jsonData))
+	return o.parseOutput(ctx, raw), nil
+}
+
+// AnalyzeStream читает NDJSON поток /api/generate и передает в onChunk новые фрагменты поля "hint"
+func (o *OllamaProvider) AnalyzeStream(ctx context.Context, input AnalysisInput, onChunk StreamHandler) (AnalysisOutput, error) {
+	prompt, err := o.buildPrompt(input)
 	if err != nil {
 		return AnalysisOutput{}, err
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := o.client.Do(req)
+	resp, err := o.generate(ctx, prompt, true)
 	if err != nil {
-		return AnalysisOutput{}, fmt.Errorf("ollama request failed: %w", err)
+		return AnalysisOutput{}, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return AnalysisOutput{}, fmt.Errorf("ollama returned status %d", resp.StatusCode)
+	stream := &hintStream{onChunk: onChunk}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var chunk ollamaResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return AnalysisOutput{}, fmt.Errorf("ollama stream decode failed: %w", err)
+		}
+		if chunk.Error != "" {
+			return AnalysisOutput{}, fmt.Errorf("ollama stream error: %s", chunk.Error)
+		}
+
+		// Модель генерирует JSON, поэтому в UI уходит только растущий текст подсказки
+		stream.Write(chunk.Response)
+
+		if chunk.Done {
+			return o.parseOutput(ctx, stream.String()), nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return AnalysisOutput{}, fmt.Errorf("ollama stream read failed: %w", err)
+	}
+
+	return AnalysisOutput{}, fmt.Errorf("ollama stream ended without done marker")
+}
+
+// parseOutput разбирает JSON ответ модели с одной попыткой исправления
+func (o *OllamaProvider) parseOutput(ctx context.Context, raw string) AnalysisOutput {
+	return parseWithRepair(ctx, "Ollama", o.prompts.Language(), raw, o.complete)
+}
+
+// complete выполняет непотоковый запрос и возвращает сырой текст ответа модели
+func (o *OllamaProvider) complete(ctx context.Context, prompt string) (string, error) {
+	resp, err := o.generate(ctx, prompt, false)
+	if err != nil {
+		return "", err
 	}
+	defer resp.Body.Close()
 
 	var ollamaResp ollamaResponse
 	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
-		return AnalysisOutput{}, err
+		return "", err
+	}
+	if ollamaResp.Error != "" {
+		return "", fmt.Errorf("ollama error: %s", ollamaResp.Error)
 	}
 
-	return AnalysisOutput{
-		Hint:       ollamaResp.Response,
-		Confidence: 0.85,
-	}, nil
+	return ollamaResp.Response, nil
 }
 
-func (o *OllamaProvider) buildPrompt(input AnalysisInput) string {
-	var prompt string
+// generate отправляет запрос в /api/generate и возвращает ответ с непрочитанным телом
+func (o *OllamaProvider) generate(ctx context.Context, prompt string, stream bool) (*http.Response, error) {
+	reqBody := ollamaRequest{
+		Model:  o.model,
+		Prompt: prompt,
+		Stream: stream,
+		Format: analysisSchema,
+	}
 
-	if input.Type == "audio" {
-		prompt = fmt.Sprintf(`Ты - эксперт SRE помощник для IT-тимлида. 
-Проанализируй следующую фразу из инцидент-митинга и дай краткую (1-2 предложения) подсказку или действие:
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
 
-Фраза: "%s"
+	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/api/generate", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
 
-Ответь кратко и по делу.`, input.TranscriptText)
-	} else if input.Type == "vision" {
-		prompt = fmt.Sprintf(`Ты - эксперт SRE помощник. 
-Проанализируй текст, извлеченный с экрана (логи, метрики):
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ollama request failed: %w", err)
+	}
 
-Текст: "%s"
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("ollama returned status %d", resp.StatusCode)
+	}
+
+	return resp, nil
+}
 
-Дай краткую оценку проблемы и предложи действие (1-2 предложения).`, input.OCRText)
+func (o *OllamaProvider) buildPrompt(input AnalysisInput) (string, error) {
+	prompt, err := o.prompts.Render(input)
+	if err != nil {
+		return "", err
 	}
 
-	return prompt
+	return prompt + jsonInstruction(o.prompts.Language()), nil
 }
 
 func (o *OllamaProvider) Health(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, providerHealthTimeout)
+	defer cancel()
+
 	req, err := http.NewRequestWithContext(ctx, "GET", o.baseURL+"/api/tags", nil)
 	if err != nil {
 		return err
@@ -0,0 +1,155 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ollamaStub - /api/generate, который отдает ответ NDJSON потоком по заданным фрагментам
+type ollamaStub struct {
+	chunks  []string
+	delay   time.Duration
+	status  int
+	lines   []string // сырые строки потока вместо chunks
+	request ollamaRequest
+}
+
+func (s *ollamaStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/generate" {
+		http.NotFound(w, r)
+		return
+	}
+	json.NewDecoder(r.Body).Decode(&s.request)
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+		return
+	}
+
+	lines := s.lines
+	if lines == nil {
+		for _, chunk := range s.chunks {
+			data, _ := json.Marshal(ollamaResponse{Response: chunk})
+			lines = append(lines, string(data))
+		}
+		lines = append(lines, `{"response":"","done":true}`)
+	}
+
+	flusher := w.(http.Flusher)
+	for _, line := range lines {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(s.delay):
+		}
+		fmt.Fprintln(w, line)
+		flusher.Flush()
+	}
+}
+
+func newTestOllama(t *testing.T, stub *ollamaStub) *OllamaProvider {
+	t.Helper()
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+
+	prompts, err := NewPromptStore("../../prompts", "en")
+	if err != nil {
+		t.Fatalf("prompts: %v", err)
+	}
+	return NewOllamaProvider(server.URL, "llama-test", prompts)
+}
+
+func TestOllamaAnalyzeStream(t *testing.T) {
+	stub := &ollamaStub{chunks: []string{
+		`{"hint": "Под `, `\"payments\" `, `рестарт`, `ует\u0`, `021", "tasks": ["kubectl logs"], `,
+		`"warnings": [], "confidence": 0.8}`,
+	}}
+	o := newTestOllama(t, stub)
+
+	var streamed strings.Builder
+	output, err := o.AnalyzeStream(context.Background(), AnalysisInput{Type: "audio", TranscriptText: "поды падают"}, func(chunk string) {
+		streamed.WriteString(chunk)
+	})
+	if err != nil {
+		t.Fatalf("AnalyzeStream: %v", err)
+	}
+
+	want := `Под "payments" рестартует!`
+	if streamed.String() != want {
+		t.Errorf("streamed %q, want %q", streamed.String(), want)
+	}
+	if output.Hint != want || output.Confidence != 0.8 || len(output.Tasks) != 1 {
+		t.Errorf("output = %+v", output)
+	}
+	if !stub.request.Stream || stub.request.Model != "llama-test" || len(stub.request.Format) == 0 {
+		t.Errorf("request = stream %v, model %q, format %d bytes", stub.request.Stream, stub.request.Model, len(stub.request.Format))
+	}
+	if !strings.Contains(stub.request.Prompt, "поды падают") {
+		t.Errorf("prompt lacks the transcript:\n%s", stub.request.Prompt)
+	}
+}
+
+func TestOllamaStreamBoundedOnlyByContext(t *testing.T) {
+	o := newTestOllama(t, &ollamaStub{
+		chunks: []string{`{"hint": "slow `, `but `, `complete", "tasks": [], "warnings": [], "confidence": 1}`},
+		delay:  50 * time.Millisecond,
+	})
+	// Медленный поток дочитывается до конца: клиент не ограничивает время ответа, это делает контекст
+	if o.client.Timeout != 0 {
+		t.Fatalf("client timeout = %v, want none", o.client.Timeout)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	output, err := o.AnalyzeStream(ctx, AnalysisInput{Type: "audio"}, func(string) {})
+	if err != nil || output.Hint != "slow but complete" {
+		t.Fatalf("output = %+v, err = %v", output, err)
+	}
+}
+
+func TestOllamaStreamStopsAtContextDeadline(t *testing.T) {
+	o := newTestOllama(t, &ollamaStub{
+		chunks: []string{`{"hint": "a`, `b`, `c`, `d`, `e"}`},
+		delay:  100 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	var streamed strings.Builder
+	_, err := o.AnalyzeStream(ctx, AnalysisInput{Type: "audio"}, func(chunk string) { streamed.WriteString(chunk) })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the context deadline", err)
+	}
+	if streamed.Len() == 0 {
+		t.Error("nothing streamed before the deadline")
+	}
+}
+
+func TestOllamaStreamErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		stub *ollamaStub
+		want string
+	}{
+		{name: "status", stub: &ollamaStub{status: http.StatusInternalServerError}, want: "status 500"},
+		{name: "stream error", stub: &ollamaStub{lines: []string{`{"response":"{\"hint\":"}`, `{"error":"model not found"}`}}, want: "model not found"},
+		{name: "bad line", stub: &ollamaStub{lines: []string{`{"response":`}}, want: "decode failed"},
+		{name: "no done marker", stub: &ollamaStub{lines: []string{`{"response":"{}"}`}}, want: "without done marker"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newTestOllama(t, tt.stub)
+			_, err := o.AnalyzeStream(context.Background(), AnalysisInput{Type: "audio"}, func(string) {})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
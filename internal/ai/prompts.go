@@ -0,0 +1,185 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// promptTypes - типы анализа, для каждого из которых обязателен шаблон
+var promptTypes = []string{"audio", "vision", "combined", "question"}
+
+const (
+	promptExt            = ".tmpl"
+	promptReloadInterval = 2 * time.Second
+)
+
+// PromptStore загружает шаблоны промптов из <dir>/<language>/<type>.tmpl
+// и перечитывает их при изменении файлов на диске.
+//
+// Все файлы *.tmpl каталога языка парсятся в один набор, поэтому шаблоны
+// могут подключать общие блоки через {{template "name" .}}.
+type PromptStore struct {
+	dir      string
+	language string
+
+	mu        sync.RWMutex
+	templates *template.Template
+	snapshot  string
+}
+
+func NewPromptStore(dir, language string) (*PromptStore, error) {
+	if dir == "" {
+		dir = "prompts"
+	}
+	if language == "" {
+		language = "ru"
+	}
+
+	store := &PromptStore{
+		dir:      dir,
+		language: language,
+	}
+
+	if err := store.reload(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("📝 Prompt templates loaded from %s (language: %s)", store.langDir(), language)
+	return store, nil
+}
+
+// Render строит промпт для ввода по шаблону его типа
+func (p *PromptStore) Render(input AnalysisInput) (string, error) {
+	p.mu.RLock()
+	templates := p.templates
+	p.mu.RUnlock()
+
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, input.Type+promptExt, input); err != nil {
+		return "", fmt.Errorf("render prompt %q: %w", input.Type, err)
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// Watch периодически проверяет каталог шаблонов и перезагружает их при изменении.
+// Если новые шаблоны невалидны, продолжают использоваться предыдущие.
+func (p *PromptStore) Watch(ctx context.Context) {
+	ticker := time.NewTicker(promptReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			snapshot, err := p.scan()
+			if err != nil {
+				log.Printf("⚠️  Failed to scan prompt templates: %v", err)
+				continue
+			}
+
+			p.mu.RLock()
+			changed := snapshot != p.snapshot
+			p.mu.RUnlock()
+
+			if !changed {
+				continue
+			}
+
+			if err := p.reload(); err != nil {
+				log.Printf("⚠️  Prompt templates not reloaded: %v (keeping previous version)", err)
+				// Запоминаем снимок, чтобы не спамить лог до следующего изменения
+				p.mu.Lock()
+				p.snapshot = snapshot
+				p.mu.Unlock()
+				continue
+			}
+
+			log.Printf("🔄 Prompt templates reloaded from %s", p.langDir())
+		}
+	}
+}
+
+// Language возвращает язык загруженных шаблонов
+func (p *PromptStore) Language() string {
+	return p.language
+}
+
+func (p *PromptStore) langDir() string {
+	return filepath.Join(p.dir, p.language)
+}
+
+// reload парсит и проверяет все шаблоны языка, после чего атомарно заменяет текущий набор
+func (p *PromptStore) reload() error {
+	snapshot, err := p.scan()
+	if err != nil {
+		return err
+	}
+
+	templates, err := template.New("").Option("missingkey=error").ParseGlob(filepath.Join(p.langDir(), "*"+promptExt))
+	if err != nil {
+		return fmt.Errorf("parse prompt templates in %s: %w", p.langDir(), err)
+	}
+
+	for _, promptType := range promptTypes {
+		name := promptType + promptExt
+		if templates.Lookup(name) == nil {
+			return fmt.Errorf("required prompt template %s is missing for language %q in %s", name, p.language, p.langDir())
+		}
+
+		// Пробный рендер ловит ошибки вроде обращения к несуществующим полям
+		sample := AnalysisInput{
+			Type:           promptType,
+			TranscriptText: "sample",
+			Speaker:        "sample",
+			OCRText:        "sample",
+			Note:           "sample",
+			App:            "sample",
+			Question:       "sample",
+			History:        []HistoryEntry{{Kind: "transcript", Speaker: "sample", Text: "sample", Time: time.Now()}},
+		}
+		if err := templates.ExecuteTemplate(&bytes.Buffer{}, name, sample); err != nil {
+			return fmt.Errorf("validate prompt template %s: %w", filepath.Join(p.langDir(), name), err)
+		}
+	}
+
+	p.mu.Lock()
+	p.templates = templates
+	p.snapshot = snapshot
+	p.mu.Unlock()
+
+	return nil
+}
+
+// scan возвращает строку с именами, размерами и временем изменения файлов шаблонов
+func (p *PromptStore) scan() (string, error) {
+	entries, err := os.ReadDir(p.langDir())
+	if err != nil {
+		return "", fmt.Errorf("read prompt dir: %w", err)
+	}
+
+	var parts []string
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != promptExt {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d:%d", entry.Name(), info.Size(), info.ModTime().UnixNano()))
+	}
+	sort.Strings(parts)
+
+	return strings.Join(parts, ";"), nil
+}
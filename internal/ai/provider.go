@@ -1,11 +1,27 @@
 package ai
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type AnalysisInput struct {
-	TranscriptText string // Текст из аудиотранскрипции
-	OCRText        string // Текст из OCR скриншотов
-	Type           string // "audio", "vision", или "combined"
+	TranscriptText string         // Текст из аудиотранскрипции
+	Speaker        string         // Кто произнес фразу, если транскрибер различает говорящих
+	OCRText        string         // Текст из OCR скриншотов
+	Note           string         // Комментарий пользователя к снимку экрана
+	App            string         // Приложение, с которого снят экран, если известно
+	Question       string         // Вопрос пользователя из UI (Type "question")
+	Type           string         // "audio", "vision", "combined" или "question"
+	History        []HistoryEntry // Недавний контекст сессии, от старых к новым
+}
+
+// HistoryEntry - одно событие сессии, которое передается модели как контекст
+type HistoryEntry struct {
+	Kind    string // "transcript", "ocr", "question", "hint", "hint_useful" или "hint_wrong"
+	Speaker string // Говорящий для "transcript", если известен
+	Text    string
+	Time    time.Time
 }
 
 type AnalysisOutput struct {
@@ -13,9 +29,16 @@ type AnalysisOutput struct {
 	Tasks      []string // Структурированные задачи
 	Warnings   []string // Предупреждения о рисках
 	Confidence float64  // Уверенность AI (0.0 - 1.0)
+	Provider   string   // Имя провайдера из цепочки, который дал ответ
 }
 
+// StreamHandler получает очередной фрагмент ответа модели по мере генерации
+type StreamHandler func(chunk string)
+
 type AIProvider interface {
 	Analyze(ctx context.Context, input AnalysisInput) (AnalysisOutput, error)
+	// AnalyzeStream работает как Analyze, но отдает текст подсказки частями
+	// через onChunk. Возвращает итоговый результат после завершения генерации.
+	AnalyzeStream(ctx context.Context, input AnalysisInput, onChunk StreamHandler) (AnalysisOutput, error)
 	Health(ctx context.Context) error
 }
@@ -0,0 +1,241 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// analysisSchema - JSON schema ответа модели, передается в поле format запроса Ollama
+var analysisSchema = json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "hint": {"type": "string"},
+    "tasks": {"type": "array", "items": {"type": "string"}},
+    "warnings": {"type": "array", "items": {"type": "string"}},
+    "confidence": {"type": "number", "minimum": 0, "maximum": 1}
+  },
+  "required": ["hint", "tasks", "warnings", "confidence"]
+}`)
+
+// jsonInstructions добавляются к каждому промпту, чтобы модель отвечала в формате AnalysisOutput
+var jsonInstructions = map[string]string{
+	"ru": `
+
+Ответь строго в формате JSON без пояснений:
+{"hint": "краткая подсказка (1-2 предложения)", "tasks": ["конкретное действие", ...], "warnings": ["риск", ...], "confidence": число от 0 до 1}`,
+	"en": `
+
+Reply strictly in JSON without any explanations:
+{"hint": "short hint (1-2 sentences)", "tasks": ["concrete action", ...], "warnings": ["risk", ...], "confidence": number from 0 to 1}`,
+}
+
+// jsonInstruction возвращает инструкцию по формату ответа для языка, по умолчанию русскую
+func jsonInstruction(language string) string {
+	if instruction, ok := jsonInstructions[language]; ok {
+		return instruction
+	}
+	return jsonInstructions["ru"]
+}
+
+// fallbackConfidence выставляется, когда модель не смогла вернуть валидный JSON
+const fallbackConfidence = 0.5
+
+// structuredOutput повторяет форму AnalysisOutput; указатели позволяют отличить отсутствующее поле от пустого
+type structuredOutput struct {
+	Hint       *string   `json:"hint"`
+	Tasks      *[]string `json:"tasks"`
+	Warnings   *[]string `json:"warnings"`
+	Confidence *float64  `json:"confidence"`
+}
+
+// decodeAnalysisOutput разбирает ответ модели и проверяет его на соответствие форме AnalysisOutput
+func decodeAnalysisOutput(raw string) (AnalysisOutput, error) {
+	var parsed structuredOutput
+	if err := json.Unmarshal([]byte(strings.TrimSpace(raw)), &parsed); err != nil {
+		return AnalysisOutput{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	switch {
+	case parsed.Hint == nil || strings.TrimSpace(*parsed.Hint) == "":
+		return AnalysisOutput{}, errors.New(`field "hint" is missing or empty`)
+	case parsed.Tasks == nil:
+		return AnalysisOutput{}, errors.New(`field "tasks" is missing`)
+	case parsed.Warnings == nil:
+		return AnalysisOutput{}, errors.New(`field "warnings" is missing`)
+	case parsed.Confidence == nil:
+		return AnalysisOutput{}, errors.New(`field "confidence" is missing`)
+	case *parsed.Confidence < 0 || *parsed.Confidence > 1:
+		return AnalysisOutput{}, fmt.Errorf(`field "confidence" is out of range: %v`, *parsed.Confidence)
+	}
+
+	return AnalysisOutput{
+		Hint:       strings.TrimSpace(*parsed.Hint),
+		Tasks:      *parsed.Tasks,
+		Warnings:   *parsed.Warnings,
+		Confidence: *parsed.Confidence,
+	}, nil
+}
+
+// completeFunc выполняет непотоковый запрос к модели и возвращает сырой текст ответа
+type completeFunc func(ctx context.Context, prompt string) (string, error)
+
+// parseWithRepair разбирает JSON ответ модели. При ошибке один раз просит модель исправить ответ
+// через complete, после чего возвращает текст как есть.
+func parseWithRepair(ctx context.Context, provider, language, raw string, complete completeFunc) AnalysisOutput {
+	output, err := decodeAnalysisOutput(raw)
+	if err == nil {
+		return output
+	}
+	log.Printf("⚠️  %s returned malformed output (%v), retrying with repair prompt", provider, err)
+
+	repaired, repairErr := complete(ctx, buildRepairPrompt(language, raw, err))
+	if repairErr == nil {
+		if output, err = decodeAnalysisOutput(repaired); err == nil {
+			return output
+		}
+		repairErr = err
+	}
+	log.Printf("⚠️  %s repair failed (%v), falling back to text output", provider, repairErr)
+
+	return textOutput(raw)
+}
+
+// hintStream накапливает потоковый JSON ответ и передает в onChunk только новые фрагменты поля "hint"
+type hintStream struct {
+	full    strings.Builder
+	sent    int
+	onChunk StreamHandler
+}
+
+func (h *hintStream) Write(chunk string) {
+	h.full.WriteString(chunk)
+	if hint := partialHint(h.full.String()); len(hint) > h.sent && h.onChunk != nil {
+		h.onChunk(hint[h.sent:])
+		h.sent = len(hint)
+	}
+}
+
+func (h *hintStream) String() string {
+	return h.full.String()
+}
+
+// textOutput - запасной вариант, когда структурированный ответ получить не удалось
+func textOutput(raw string) AnalysisOutput {
+	return AnalysisOutput{
+		Hint:       strings.TrimSpace(raw),
+		Confidence: fallbackConfidence,
+	}
+}
+
+// buildRepairPrompt просит модель исправить невалидный ответ
+func buildRepairPrompt(language, raw string, cause error) string {
+	if language == "en" {
+		return fmt.Sprintf(`Your previous answer is not valid JSON of the required format.
+Error: %s
+
+Previous answer:
+%s
+
+Fix the answer while keeping its meaning.`, cause, raw) + jsonInstruction(language)
+	}
+
+	return fmt.Sprintf(`Твой предыдущий ответ не является корректным JSON нужного формата.
+Ошибка: %s
+
+Предыдущий ответ:
+%s
+
+Исправь ответ, сохранив смысл.`, cause, raw) + jsonInstruction(language)
+}
+
+// partialHint извлекает из незавершенного JSON ответа уже сгенерированную часть поля "hint"
+func partialHint(raw string) string {
+	idx := strings.Index(raw, `"hint"`)
+	if idx < 0 {
+		return ""
+	}
+	rest := strings.TrimLeft(raw[idx+len(`"hint"`):], " \t\r\n")
+	if !strings.HasPrefix(rest, ":") {
+		return ""
+	}
+	rest = strings.TrimLeft(rest[1:], " \t\r\n")
+	if !strings.HasPrefix(rest, `"`) {
+		return ""
+	}
+	rest = rest[1:]
+
+	// Ищем закрывающую кавычку, пропуская экранированные символы
+	end := len(rest)
+	for i := 0; i < len(rest); i++ {
+		if rest[i] == '\\' {
+			i++
+			continue
+		}
+		if rest[i] == '"' {
+			end = i
+			break
+		}
+	}
+	body := rest[:end]
+	if end == len(rest) {
+		body = trimIncompleteTail(body)
+	}
+
+	// Незавершенная escape-последовательность в конце фрагмента обрезается до следующего чанка
+	for len(body) > 0 {
+		var hint string
+		if err := json.Unmarshal([]byte(`"`+body+`"`), &hint); err == nil {
+			return hint
+		}
+		cut := strings.LastIndex(body, `\`)
+		if cut < 0 {
+			return ""
+		}
+		body = body[:cut]
+	}
+	return ""
+}
+
+// trimIncompleteTail обрезает конец незакрытой строки, который декодируется иначе, чем после
+// следующего чанка: первую половину суррогатной пары (\ud83d без \ude00) и неполный UTF-8
+// символ. Иначе в UI ушел бы U+FFFD, а следующий фрагмент сместился бы на байт.
+func trimIncompleteTail(body string) string {
+	for i := len(body) - 1; i >= 0 && i >= len(body)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(body[i]) {
+			if !utf8.FullRuneInString(body[i:]) {
+				body = body[:i]
+			}
+			break
+		}
+	}
+
+	const escapeLen = len(`\ud800`)
+	if len(body) >= escapeLen {
+		start := len(body) - escapeLen
+		if strings.HasPrefix(body[start:], `\u`) && isHighSurrogate(body[start+2:]) && !escaped(body, start) {
+			body = body[:start]
+		}
+	}
+	return body
+}
+
+// isHighSurrogate сообщает, что hex код - первая половина суррогатной пары (D800-DBFF)
+func isHighSurrogate(hex string) bool {
+	code, err := strconv.ParseUint(hex, 16, 32)
+	return err == nil && code >= 0xD800 && code <= 0xDBFF
+}
+
+// escaped сообщает, что символ body[i] экранирован нечетным числом обратных слешей перед ним
+func escaped(body string, i int) bool {
+	slashes := 0
+	for j := i - 1; j >= 0 && body[j] == '\\'; j-- {
+		slashes++
+	}
+	return slashes%2 == 1
+}
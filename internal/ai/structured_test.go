@@ -0,0 +1,205 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestPartialHint(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{name: "empty", raw: "", want: ""},
+		{name: "no hint yet", raw: `{"tas`, want: ""},
+		{name: "key only", raw: `{"hint"`, want: ""},
+		{name: "before value", raw: `{"hint": `, want: ""},
+		{name: "not a string", raw: `{"hint": null`, want: ""},
+		{name: "open string", raw: `{"hint": "Под`, want: "Под"},
+		{name: "closed string", raw: `{"hint": "Pods restart", "tasks": ["x"`, want: "Pods restart"},
+		{name: "whitespace around colon", raw: "{\n  \"hint\" :\n \"ok", want: "ok"},
+		{name: "escaped quote", raw: `{"hint": "say \"hi\" to`, want: `say "hi" to`},
+		{name: "escaped quote at the end", raw: `{"hint": "say \"`, want: `say "`},
+		{name: "backslash at the end", raw: `{"hint": "a\`, want: "a"},
+		{name: "escaped backslash", raw: `{"hint": "C:\\`, want: `C:\`},
+		{name: "escaped backslash before quote", raw: `{"hint": "C:\\", "tasks"`, want: `C:\`},
+		{name: "split unicode escape", raw: `{"hint": "a\u04`, want: "a"},
+		{name: "unicode escape", raw: `{"hint": "a\u0410`, want: "aА"},
+		{name: "newline escape", raw: `{"hint": "a\nb`, want: "a\nb"},
+		{name: "high surrogate only", raw: `{"hint": "ok \ud83d`, want: "ok "},
+		{name: "surrogate pair", raw: `{"hint": "ok \ud83d\ude00`, want: "ok 😀"},
+		{name: "literal backslash-u", raw: `{"hint": "\\ud83d`, want: `\ud83d`},
+		{name: "split utf-8", raw: `{"hint": "Под` + "\xd0", want: "Под"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := partialHint(tt.raw); got != tt.want {
+				t.Errorf("partialHint(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHintStreamChunkBoundaries(t *testing.T) {
+	raw := `{"hint": "Под \"payments\" 😀 в CrashLoop\\Back\u0041", "tasks": [], "warnings": [], "confidence": 0.8}`
+	want := "Под \"payments\" 😀 в CrashLoop\\BackA"
+
+	// Разрезаем ответ на фрагменты всех длин, в том числе посреди escape и UTF-8 символов
+	for size := 1; size <= 7; size++ {
+		var got strings.Builder
+		stream := &hintStream{onChunk: func(chunk string) {
+			if !utf8.ValidString(chunk) || strings.ContainsRune(chunk, utf8.RuneError) {
+				t.Errorf("size %d: broken chunk %q", size, chunk)
+			}
+			got.WriteString(chunk)
+		}}
+		for i := 0; i < len(raw); i += size {
+			stream.Write(raw[i:min(i+size, len(raw))])
+		}
+
+		if got.String() != want {
+			t.Errorf("size %d: streamed %q, want %q", size, got.String(), want)
+		}
+		if stream.String() != raw {
+			t.Errorf("size %d: accumulated %q", size, stream.String())
+		}
+	}
+}
+
+func TestHintStreamEmojiEscapeAcrossChunks(t *testing.T) {
+	var chunks []string
+	stream := &hintStream{onChunk: func(chunk string) { chunks = append(chunks, chunk) }}
+	for _, chunk := range []string{`{"hint": "ok `, `\ud83d`, `\ude00`, ` done"}`} {
+		stream.Write(chunk)
+	}
+	if want := []string{"ok ", "😀", " done"}; !reflect.DeepEqual(chunks, want) {
+		t.Errorf("chunks = %q, want %q", chunks, want)
+	}
+}
+
+func TestDecodeAnalysisOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    AnalysisOutput
+		wantErr string
+	}{
+		{
+			name: "valid",
+			raw:  ` {"hint": " Pods restart ", "tasks": ["kubectl logs"], "warnings": [], "confidence": 0.7} `,
+			want: AnalysisOutput{Hint: "Pods restart", Tasks: []string{"kubectl logs"}, Warnings: []string{}, Confidence: 0.7},
+		},
+		{name: "not json", raw: "Pods restart", wantErr: "invalid JSON"},
+		{name: "truncated", raw: `{"hint": "Pods`, wantErr: "invalid JSON"},
+		{name: "wrong type", raw: `{"hint": 1, "tasks": [], "warnings": [], "confidence": 1}`, wantErr: "invalid JSON"},
+		{name: "missing hint", raw: `{"tasks": [], "warnings": [], "confidence": 0.5}`, wantErr: `"hint" is missing or empty`},
+		{name: "blank hint", raw: `{"hint": "  ", "tasks": [], "warnings": [], "confidence": 0.5}`, wantErr: `"hint" is missing or empty`},
+		{name: "missing tasks", raw: `{"hint": "x", "warnings": [], "confidence": 0.5}`, wantErr: `"tasks" is missing`},
+		{name: "null tasks", raw: `{"hint": "x", "tasks": null, "warnings": [], "confidence": 0.5}`, wantErr: `"tasks" is missing`},
+		{name: "missing warnings", raw: `{"hint": "x", "tasks": [], "confidence": 0.5}`, wantErr: `"warnings" is missing`},
+		{name: "missing confidence", raw: `{"hint": "x", "tasks": [], "warnings": []}`, wantErr: `"confidence" is missing`},
+		{name: "confidence above 1", raw: `{"hint": "x", "tasks": [], "warnings": [], "confidence": 1.5}`, wantErr: "out of range"},
+		{name: "negative confidence", raw: `{"hint": "x", "tasks": [], "warnings": [], "confidence": -0.1}`, wantErr: "out of range"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeAnalysisOutput(tt.raw)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeAnalysisOutput: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("output = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWithRepair(t *testing.T) {
+	const valid = `{"hint": "fixed", "tasks": [], "warnings": [], "confidence": 0.9}`
+	const malformed = `Sure! {"hint": "Pods restart"`
+
+	tests := []struct {
+		name     string
+		raw      string
+		repaired string
+		err      error
+		want     AnalysisOutput
+		calls    int
+	}{
+		{
+			name:  "valid answer",
+			raw:   valid,
+			want:  AnalysisOutput{Hint: "fixed", Tasks: []string{}, Warnings: []string{}, Confidence: 0.9},
+			calls: 0,
+		},
+		{
+			name:     "repair succeeds",
+			raw:      malformed,
+			repaired: valid,
+			want:     AnalysisOutput{Hint: "fixed", Tasks: []string{}, Warnings: []string{}, Confidence: 0.9},
+			calls:    1,
+		},
+		{
+			name:     "repair still invalid",
+			raw:      malformed,
+			repaired: `{"hint": "still no tasks"}`,
+			want:     AnalysisOutput{Hint: malformed, Confidence: fallbackConfidence},
+			calls:    1,
+		},
+		{
+			name:  "repair request fails",
+			raw:   " plain text hint ",
+			err:   errors.New("connection reset"),
+			want:  AnalysisOutput{Hint: "plain text hint", Confidence: fallbackConfidence},
+			calls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prompts []string
+			complete := func(ctx context.Context, prompt string) (string, error) {
+				prompts = append(prompts, prompt)
+				return tt.repaired, tt.err
+			}
+
+			got := parseWithRepair(context.Background(), "Test", "en", tt.raw, complete)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("output = %+v, want %+v", got, tt.want)
+			}
+			if len(prompts) != tt.calls {
+				t.Fatalf("repair requests = %d, want %d", len(prompts), tt.calls)
+			}
+			if tt.calls > 0 {
+				prompt := prompts[0]
+				if !strings.Contains(prompt, tt.raw) || !strings.Contains(prompt, "Error: ") || !strings.Contains(prompt, "Reply strictly in JSON") {
+					t.Errorf("repair prompt lacks the answer, the error or the format:\n%s", prompt)
+				}
+			}
+		})
+	}
+}
+
+func TestRepairPromptLanguage(t *testing.T) {
+	cause := errors.New(`field "tasks" is missing`)
+	if prompt := buildRepairPrompt("ru", "{}", cause); !strings.Contains(prompt, "Ошибка: field \"tasks\" is missing") || !strings.Contains(prompt, "Ответь строго в формате JSON") {
+		t.Errorf("ru prompt:\n%s", prompt)
+	}
+	// Неизвестный язык получает русскую инструкцию, как и промпты
+	if prompt := buildRepairPrompt("de", "{}", cause); !strings.Contains(prompt, "Ответь строго в формате JSON") {
+		t.Errorf("fallback prompt:\n%s", prompt)
+	}
+}
@@ -30,6 +30,7 @@ func (m *MockTranscriber) Transcribe(ctx context.Context, audioData []byte) (str
 		"Сервер не отвечает. Проверьте логи в /var/log/app.log",
 		"Database connection timeout. Возможно, network issue.",
 		"API возвращает 500 ошибки последний час.",
+		"Покажи логи payments, там что-то странное.",
 	}
 
 	transcript := mockTranscripts[m.counter%len(mockTranscripts)]
@@ -44,6 +45,23 @@ func (m *MockTranscriber) Transcribe(ctx context.Context, audioData []byte) (str
 	}
 }
 
+// TranscribeTurns имитирует диаризацию: реплики по очереди приписываются участникам инцидент-митинга
+func (m *MockTranscriber) TranscribeTurns(ctx context.Context, audioData []byte) ([]SpeakerTurn, error) {
+	text, err := m.Transcribe(ctx, audioData)
+	if err != nil {
+		return nil, err
+	}
+
+	mockSpeakers := []string{"Incident Commander", "SRE on-call", "DBA"}
+
+	return []SpeakerTurn{{
+		Text:       text,
+		Speaker:    mockSpeakers[m.counter%len(mockSpeakers)],
+		Language:   "ru",
+		Confidence: 0.9,
+	}}, nil
+}
+
 func (m *MockTranscriber) Close() error {
 	log.Println("🎤 MockTranscriber closed")
 	return nil
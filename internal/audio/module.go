@@ -2,25 +2,39 @@ package audio
 
 import (
 	"context"
+	"errors"
+	"io"
 	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"cluely/internal/config"
 )
 
+// stopTimeout ограничивает ожидание горутины захвата при остановке модуля
+const stopTimeout = 2 * time.Second
+
 // Module управляет захватом и транскрипцией аудио
 type Module struct {
 	cfg         config.AudioConfig
 	transcriber Transcriber
-	transcripts chan string
+	source      AudioSource
+	transcripts chan Transcript
+	partials    chan string
 	stopCh      chan struct{}
+	wg          sync.WaitGroup
 	isRunning   bool
+	paused      atomic.Bool
 }
 
 func NewModule(cfg config.AudioConfig) *Module {
 	return &Module{
 		cfg:         cfg,
-		transcripts: make(chan string, 10),
+		transcripts: make(chan Transcript, 10),
+		partials:    make(chan string, 10),
 		stopCh:      make(chan struct{}),
 	}
 }
@@ -32,7 +46,7 @@ func (m *Module) Start(ctx context.Context) error {
 	}
 
 	// Создаем транскрибер на основе конфига
-	transcriber, err := NewTranscriber(m.cfg.TranscriberType, m.cfg.TranscriberConfig)
+	transcriber, err := NewTranscriber(m.cfg.TranscriberType, m.transcriberOptions())
 	if err != nil {
 		return err
 	}
@@ -42,16 +56,251 @@ func (m *Module) Start(ctx context.Context) error {
 	}
 
 	m.transcriber = transcriber
+
+	if m.cfg.Source == "" || m.cfg.Source == "mock" {
+		// Запускаем горутину для симуляции аудиоввода (в mock режиме)
+		m.wg.Add(1)
+		go m.simulateAudioCapture(ctx)
+	} else {
+		source, err := NewAudioSource(m.cfg.Source, m.cfg.SourcePath, m.sampleRate())
+		if err != nil {
+			transcriber.Close()
+			return err
+		}
+		m.source = source
+		m.wg.Add(1)
+		go m.captureAudio(ctx)
+	}
+
 	m.isRunning = true
 
-	// Запускаем горутину для симуляции аудиоввода (в mock режиме)
-	go m.simulateAudioCapture(ctx)
+	if partial, ok := transcriber.(PartialTranscriber); ok {
+		go m.forwardPartials(ctx, partial.Partials())
+	}
 
-	log.Printf("✅ Audio Module started (transcriber: %s)", m.cfg.TranscriberType)
+	log.Printf("✅ Audio Module started (transcriber: %s, source: %s)", m.cfg.TranscriberType, m.cfg.Source)
 	return nil
 }
 
+// transcriberOptions дополняет transcriber_config общими параметрами аудио
+func (m *Module) transcriberOptions() map[string]string {
+	options := make(map[string]string, len(m.cfg.TranscriberConfig)+1)
+	for key, value := range m.cfg.TranscriberConfig {
+		options[key] = value
+	}
+	if _, ok := options["sample_rate"]; !ok {
+		options["sample_rate"] = strconv.Itoa(m.sampleRate())
+	}
+	return options
+}
+
+func (m *Module) sampleRate() int {
+	if m.cfg.SampleRate > 0 {
+		return m.cfg.SampleRate
+	}
+	return 16000
+}
+
+// frameSize возвращает размер фрейма в байтах: audio.buffer_size сэмплов по 16 бит
+func (m *Module) frameSize() int {
+	samples := m.cfg.BufferSize
+	if samples <= 0 {
+		samples = 1024
+	}
+	return samples * pcmBytesPerSample
+}
+
+// captureAudio читает PCM из источника фреймами по audio.buffer_size сэмплов и передает их транскриберу
+func (m *Module) captureAudio(ctx context.Context) {
+	defer m.wg.Done()
+
+	if err := m.source.Open(); err != nil {
+		// errSourceClosed - модуль остановили, пока источник открывался
+		if !errors.Is(err, errSourceClosed) {
+			log.Printf("❌ Audio source failed: %v", err)
+		}
+		return
+	}
+
+	vad := newVoiceDetector(
+		m.cfg.SilenceThreshold,
+		m.sampleRate(),
+		time.Duration(m.cfg.VADHangoverMs)*time.Millisecond,
+		time.Duration(m.cfg.VADMinUtteranceMs)*time.Millisecond,
+		time.Duration(m.cfg.VADMaxUtteranceMs)*time.Millisecond,
+	)
+	var utteranceStart time.Time
+	defer func() {
+		// Дорабатываем фразу, оборванную концом потока
+		if utterance, ended := vad.Flush(); ended {
+			m.endUtterance(ctx, utterance, utteranceStart)
+		}
+	}()
+
+	_, streaming := m.transcriber.(PartialTranscriber)
+
+	for {
+		frame := make([]byte, m.frameSize())
+		_, err := io.ReadFull(m.source, frame)
+		if err != nil {
+			select {
+			case <-m.stopCh:
+			case <-ctx.Done():
+			default:
+				if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+					log.Println("🎙️  Audio source finished")
+				} else {
+					log.Printf("❌ Audio source read error: %v", err)
+				}
+			}
+			return
+		}
+
+		// На паузе поток продолжает читаться, чтобы источник не переполнился, но речь не распознается;
+		// фраза, начатая до паузы, дорабатывается
+		if m.paused.Load() {
+			if utterance, ended := vad.Flush(); ended && !m.endUtterance(ctx, utterance, utteranceStart) {
+				return
+			}
+			utteranceStart = time.Time{}
+			continue
+		}
+
+		utterance, voiced, ended := vad.Push(frame)
+		if voiced && utteranceStart.IsZero() {
+			utteranceStart = time.Now().Add(-vad.duration(len(frame)))
+		}
+
+		// Потоковые транскриберы получают речь по фреймам, чтобы показывать промежуточный текст
+		if streaming && voiced && !m.transcribe(ctx, frame, utteranceStart) {
+			return
+		}
+		if ended {
+			if !m.endUtterance(ctx, utterance, utteranceStart) {
+				return
+			}
+			utteranceStart = time.Time{}
+		}
+	}
+}
+
+// endUtterance передает транскриберу завершенную фразу. Потоковым транскриберам фраза уже
+// отправлена по фреймам, им достаточно сигнала о конце. utterance == nil - фраза короче
+// vad_min_utterance_ms (щелчок, кашель): потоковый распознаватель все равно завершается, чтобы
+// сбросить его состояние, но текст не публикуется. Возвращает false при остановке модуля.
+func (m *Module) endUtterance(ctx context.Context, utterance []byte, start time.Time) bool {
+	if _, streaming := m.transcriber.(PartialTranscriber); !streaming {
+		if utterance == nil {
+			return true
+		}
+		return m.transcribe(ctx, utterance, start)
+	}
+
+	finalizer, ok := m.transcriber.(UtteranceFinalizer)
+	if !ok {
+		return true
+	}
+
+	text, err := finalizer.FinishUtterance(ctx)
+	if err != nil {
+		log.Printf("⚠️  Transcription error: %v", err)
+		return true
+	}
+	if utterance == nil {
+		if strings.TrimSpace(text) != "" {
+			log.Printf("🔇 Short utterance discarded (%d chars)", len(text))
+		}
+		m.clearCaption()
+		return true
+	}
+	return m.publish(ctx, m.newTranscript(SpeakerTurn{Text: text}, start))
+}
+
+// clearCaption убирает живой субтитр отброшенной фразы
+func (m *Module) clearCaption() {
+	select {
+	case m.partials <- "":
+	default:
+	}
+}
+
+// transcribe распознает фрагмент аудио, начавшийся в start, и публикует реплики.
+// Возвращает false при остановке модуля.
+func (m *Module) transcribe(ctx context.Context, audioData []byte, start time.Time) bool {
+	turns, err := m.recognize(ctx, audioData)
+	if err != nil {
+		log.Printf("⚠️  Transcription error: %v", err)
+		return true
+	}
+
+	for _, turn := range turns {
+		if !m.publish(ctx, m.newTranscript(turn, start)) {
+			return false
+		}
+	}
+	return true
+}
+
+// recognize возвращает реплики говорящих, если бэкенд их различает, иначе одну реплику на весь фрагмент
+func (m *Module) recognize(ctx context.Context, audioData []byte) ([]SpeakerTurn, error) {
+	if speakers, ok := m.transcriber.(SpeakerTranscriber); ok {
+		return speakers.TranscribeTurns(ctx, audioData)
+	}
+
+	text, err := m.transcriber.Transcribe(ctx, audioData)
+	if err != nil {
+		return nil, err
+	}
+
+	turn := SpeakerTurn{Text: text}
+	// Для потоковых транскриберов фрагмент - лишь последний фрейм фразы, его длина ничего не говорит о конце
+	if _, streaming := m.transcriber.(PartialTranscriber); !streaming {
+		turn.Duration = time.Duration(len(audioData)/pcmBytesPerSample) * time.Second / time.Duration(m.sampleRate())
+	}
+	return []SpeakerTurn{turn}, nil
+}
+
+// newTranscript переводит реплику с относительным временем в событие с абсолютными метками
+func (m *Module) newTranscript(turn SpeakerTurn, start time.Time) Transcript {
+	transcript := Transcript{
+		Text:       strings.TrimSpace(turn.Text),
+		Start:      start.Add(turn.Offset),
+		Speaker:    turn.Speaker,
+		Language:   turn.Language,
+		Confidence: turn.Confidence,
+	}
+
+	if turn.Duration > 0 {
+		transcript.End = transcript.Start.Add(turn.Duration)
+	} else {
+		transcript.End = time.Now()
+	}
+	if transcript.Language == "" {
+		transcript.Language = m.cfg.TranscriberConfig["language"]
+	}
+
+	return transcript
+}
+
+// publish отправляет непустую транскрипцию агенту. Возвращает false при остановке модуля.
+func (m *Module) publish(ctx context.Context, transcript Transcript) bool {
+	if transcript.Text == "" {
+		return true
+	}
+
+	select {
+	case m.transcripts <- transcript:
+		return true
+	case <-m.stopCh:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
 func (m *Module) simulateAudioCapture(ctx context.Context) {
+	defer m.wg.Done()
+
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
 
@@ -62,22 +311,61 @@ func (m *Module) simulateAudioCapture(ctx context.Context) {
 		case <-m.stopCh:
 			return
 		case <-ticker.C:
+			if m.paused.Load() {
+				continue
+			}
 			// Симулируем захват аудио
-			if transcript, err := m.transcriber.Transcribe(ctx, nil); err == nil && transcript != "" {
-				select {
-				case m.transcripts <- transcript:
-				case <-ctx.Done():
-					return
-				}
+			if !m.transcribe(ctx, nil, time.Now()) {
+				return
+			}
+		}
+	}
+}
+
+// forwardPartials пересылает промежуточные результаты транскрибера для живых субтитров
+func (m *Module) forwardPartials(ctx context.Context, partials <-chan string) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.stopCh:
+			return
+		case partial := <-partials:
+			select {
+			case m.partials <- partial:
+			default:
+				// Субтитр устарел раньше, чем его забрали - пропускаем
 			}
 		}
 	}
 }
 
-func (m *Module) TranscriptChannel() <-chan string {
+// SetPaused приостанавливает или возобновляет распознавание речи без остановки модуля
+func (m *Module) SetPaused(paused bool) {
+	if m.paused.Swap(paused) == paused {
+		return
+	}
+	if paused {
+		log.Println("⏸️  Audio capture paused")
+	} else {
+		log.Println("▶️  Audio capture resumed")
+	}
+}
+
+// Paused сообщает, приостановлено ли распознавание речи
+func (m *Module) Paused() bool {
+	return m.paused.Load()
+}
+
+func (m *Module) TranscriptChannel() <-chan Transcript {
 	return m.transcripts
 }
 
+// PartialChannel возвращает промежуточные результаты распознавания (живые субтитры)
+func (m *Module) PartialChannel() <-chan string {
+	return m.partials
+}
+
 func (m *Module) Stop() {
 	if !m.isRunning {
 		return
@@ -86,10 +374,28 @@ func (m *Module) Stop() {
 	m.isRunning = false
 	close(m.stopCh)
 
-	if m.transcriber != nil {
-		m.transcriber.Close()
+	// Закрытие источника разблокирует ожидающее чтение
+	if m.source != nil {
+		m.source.Close()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		if m.transcriber != nil {
+			m.transcriber.Close()
+		}
+		close(m.transcripts)
+	case <-time.After(stopTimeout):
+		// Чтение из терминала не прерывается закрытием stdin.
+		// Канал не закрываем, чтобы зависшая горутина не упала при отправке.
+		log.Println("⚠️  Audio capture did not stop in time, leaving it to exit with the process")
 	}
 
-	close(m.transcripts)
 	log.Println("🛑 Audio Module stopped")
 }
@@ -0,0 +1,71 @@
+package audio
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cluely/internal/config"
+)
+
+// streamingStub - потоковый транскрибер для тестов: на конец фразы отдает заданный текст
+type streamingStub struct {
+	text     string
+	finished int
+}
+
+func (s *streamingStub) Initialize() error       { return nil }
+func (s *streamingStub) Close() error            { return nil }
+func (s *streamingStub) Partials() <-chan string { return nil }
+
+func (s *streamingStub) Transcribe(ctx context.Context, audioData []byte) (string, error) {
+	return "", nil
+}
+
+func (s *streamingStub) FinishUtterance(ctx context.Context) (string, error) {
+	s.finished++
+	return s.text, nil
+}
+
+func TestEndUtteranceDiscardsShortStreamingUtterance(t *testing.T) {
+	stub := &streamingStub{text: "кхм"}
+	m := NewModule(config.AudioConfig{})
+	m.transcriber = stub
+	m.partials <- "кх"
+
+	if !m.endUtterance(context.Background(), nil, time.Now()) {
+		t.Fatal("endUtterance reported stop")
+	}
+
+	if stub.finished != 1 {
+		t.Errorf("FinishUtterance called %d times, want 1 to reset the recognizer", stub.finished)
+	}
+	select {
+	case transcript := <-m.transcripts:
+		t.Errorf("short utterance published: %+v", transcript)
+	default:
+	}
+	<-m.partials
+	if caption := <-m.partials; caption != "" {
+		t.Errorf("caption = %q, want it cleared", caption)
+	}
+}
+
+func TestEndUtterancePublishesStreamingUtterance(t *testing.T) {
+	stub := &streamingStub{text: " покажи поды "}
+	m := NewModule(config.AudioConfig{})
+	m.transcriber = stub
+
+	if !m.endUtterance(context.Background(), make([]byte, 3200), time.Now()) {
+		t.Fatal("endUtterance reported stop")
+	}
+
+	select {
+	case transcript := <-m.transcripts:
+		if transcript.Text != "покажи поды" {
+			t.Errorf("text = %q", transcript.Text)
+		}
+	default:
+		t.Fatal("utterance was not published")
+	}
+}
@@ -0,0 +1,131 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// errSourceClosed - источник закрыт раньше, чем успел открыться
+var errSourceClosed = errors.New("audio source closed")
+
+// Сколько Close пытается разбудить Open, ждущий писателя в FIFO
+const fifoWakeTimeout = time.Second
+
+// PCMSource читает сырой PCM из именованного канала (FIFO) или stdin.
+// Подходит для связки с внешним захватом звука на Linux, например:
+//
+//	parec --format=s16le --rate=16000 --channels=1 > /tmp/cluely.pcm
+//	arecord -f S16_LE -r 16000 -c 1 -t raw | cluely
+//
+// Open и Read вызываются из горутины захвата, Close - из Stop параллельно с ними.
+type PCMSource struct {
+	path string
+
+	mu      sync.Mutex // защищает reader, opening и closed
+	reader  io.ReadCloser
+	opening bool // Open ждет писателя в FIFO
+	closed  bool
+}
+
+func NewPCMSource(path string) *PCMSource {
+	return &PCMSource{path: path}
+}
+
+// Open открывает FIFO; вызов блокируется, пока в канал не начнет писать процесс захвата
+// или пока источник не закроют через Close
+func (p *PCMSource) Open() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return errSourceClosed
+	}
+	if p.path == "" || p.path == "-" {
+		p.reader = os.Stdin
+		p.mu.Unlock()
+		log.Println("🎙️  PCM source: reading from stdin")
+		return nil
+	}
+	p.opening = true
+	p.mu.Unlock()
+
+	file, err := os.Open(p.path)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.opening = false
+	if err != nil {
+		return fmt.Errorf("open pcm source: %w", err)
+	}
+	if p.closed {
+		file.Close()
+		return errSourceClosed
+	}
+	p.reader = file
+
+	log.Printf("🎙️  PCM source opened: %s", p.path)
+	return nil
+}
+
+func (p *PCMSource) Read(buf []byte) (int, error) {
+	p.mu.Lock()
+	reader := p.reader
+	p.mu.Unlock()
+
+	if reader == nil {
+		return 0, io.EOF
+	}
+	return reader.Read(buf)
+}
+
+// Close может вызываться параллельно с Open и Read, чтобы прервать ожидание писателя или данных
+func (p *PCMSource) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	reader := p.reader
+	opening := p.opening
+	p.mu.Unlock()
+
+	if opening {
+		p.wakeOpen()
+	}
+	if reader == nil {
+		return nil
+	}
+	return reader.Close()
+}
+
+// wakeOpen прерывает Open, который ждет писателя в FIFO: подключается к каналу как писатель
+// без блокировки и сразу отключается. Open мог еще не дойти до системного вызова - тогда
+// писателю не к кому подключиться, и попытка повторяется.
+func (p *PCMSource) wakeOpen() {
+	info, err := os.Stat(p.path)
+	if err != nil || info.Mode()&os.ModeNamedPipe == 0 {
+		return
+	}
+
+	deadline := time.Now().Add(fifoWakeTimeout)
+	for time.Now().Before(deadline) {
+		p.mu.Lock()
+		opening := p.opening
+		p.mu.Unlock()
+		if !opening {
+			return
+		}
+
+		if writer, err := os.OpenFile(p.path, os.O_WRONLY|syscall.O_NONBLOCK, 0); err == nil {
+			writer.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
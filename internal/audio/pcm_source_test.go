@@ -0,0 +1,92 @@
+//go:build unix
+
+package audio
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func makeFIFO(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audio.pcm")
+	if err := syscall.Mkfifo(path, 0o600); err != nil {
+		t.Skipf("mkfifo: %v", err)
+	}
+	return path
+}
+
+func TestPCMSourceCloseInterruptsPendingOpen(t *testing.T) {
+	source := NewPCMSource(makeFIFO(t))
+
+	opened := make(chan error, 1)
+	go func() { opened <- source.Open() }()
+
+	// Даем Open дойти до ожидания писателя
+	time.Sleep(50 * time.Millisecond)
+	if err := source.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	select {
+	case err := <-opened:
+		if !errors.Is(err, errSourceClosed) {
+			t.Errorf("Open = %v, want errSourceClosed", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Open still blocked after Close")
+	}
+
+	if n, err := source.Read(make([]byte, 16)); n != 0 || err != io.EOF {
+		t.Errorf("Read after Close = %d, %v; want EOF", n, err)
+	}
+}
+
+func TestPCMSourceCloseBeforeOpen(t *testing.T) {
+	source := NewPCMSource(makeFIFO(t))
+	source.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- source.Open() }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, errSourceClosed) {
+			t.Errorf("Open = %v, want errSourceClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Open blocked on a closed source")
+	}
+}
+
+func TestPCMSourceReadsFromWriter(t *testing.T) {
+	path := makeFIFO(t)
+	source := NewPCMSource(path)
+
+	go func() {
+		writer, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return
+		}
+		writer.Write([]byte{1, 2, 3, 4})
+		writer.Close()
+	}()
+
+	if err := source.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	data, err := io.ReadAll(source)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(data) != string([]byte{1, 2, 3, 4}) {
+		t.Errorf("data = %v", data)
+	}
+	if err := source.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package audio
+
+import (
+	"fmt"
+	"io"
+)
+
+// AudioSource - источник сырого PCM (16 бит, моно, little-endian) с частотой audio.sample_rate.
+// Read может блокироваться до появления данных; io.EOF означает конец потока.
+type AudioSource interface {
+	io.Reader
+	Open() error
+	Close() error
+}
+
+// NewAudioSource создает источник по типу из конфига:
+//   - "wav": WAV файл из path, воспроизводится в реальном времени
+//   - "pcm": сырой PCM из FIFO по path или из stdin, если path пустой или "-"
+func NewAudioSource(sourceType, path string, sampleRate int) (AudioSource, error) {
+	switch sourceType {
+	case "wav":
+		if path == "" {
+			return nil, fmt.Errorf("audio.source_path is required for wav source")
+		}
+		return NewWAVFileSource(path, sampleRate), nil
+	case "pcm":
+		return NewPCMSource(path), nil
+	default:
+		return nil, fmt.Errorf("unknown audio source: %s", sourceType)
+	}
+}
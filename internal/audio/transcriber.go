@@ -11,12 +11,33 @@ type Transcriber interface {
 	Close() error
 }
 
+// PartialTranscriber - транскрибер, который отдает промежуточные результаты до завершения фразы
+type PartialTranscriber interface {
+	Transcriber
+	Partials() <-chan string
+}
+
+// UtteranceFinalizer - потоковый транскрибер, которому модуль сообщает о конце фразы по VAD,
+// чтобы получить финальный текст, не дожидаясь собственного детектора пауз бэкенда
+type UtteranceFinalizer interface {
+	FinishUtterance(ctx context.Context) (string, error)
+}
+
+// SpeakerTranscriber - транскрибер, который разбивает фрагмент на реплики разных говорящих
+type SpeakerTranscriber interface {
+	TranscribeTurns(ctx context.Context, audioData []byte) ([]SpeakerTurn, error)
+}
+
 func NewTranscriber(transcriberType string, config map[string]string) (Transcriber, error) {
 	switch transcriberType {
 	case "azure":
 		// TODO: Implement Azure Speech Services
 		// For now, fallback to mock
 		return NewMockTranscriber(), nil
+	case "vosk":
+		return NewVoskTranscriber(config), nil
+	case "whisper":
+		return NewWhisperTranscriber(config), nil
 	case "mock":
 		return NewMockTranscriber(), nil
 	default:
@@ -0,0 +1,24 @@
+package audio
+
+import "time"
+
+// Transcript - распознанная фраза одного говорящего
+type Transcript struct {
+	Text       string
+	Start      time.Time
+	End        time.Time
+	Speaker    string  // Метка говорящего, пустая если бэкенд не различает говорящих
+	Language   string  // Язык речи, из ответа бэкенда или transcriber_config
+	Confidence float64 // Уверенность распознавания (0.0 - 1.0), 0 если бэкенд ее не сообщает
+}
+
+// SpeakerTurn - реплика внутри фрагмента аудио, которую вернул бэкенд с поддержкой диаризации.
+// Offset и Duration отсчитываются от начала переданного фрагмента.
+type SpeakerTurn struct {
+	Text       string
+	Speaker    string
+	Offset     time.Duration
+	Duration   time.Duration
+	Language   string
+	Confidence float64
+}
@@ -0,0 +1,123 @@
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+const (
+	defaultSilenceThreshold = 0.02
+	defaultVADHangover      = 500 * time.Millisecond
+	defaultMinUtterance     = 300 * time.Millisecond
+	defaultMaxUtterance     = 15 * time.Second
+)
+
+// voiceDetector - энергетический детектор речи. Фрейм считается речью, если его RMS
+// (в долях от полной шкалы 16 бит) не ниже порога audio.silence_threshold.
+// Фраза завершается, когда тишина длится дольше hangover, или принудительно по достижении maxLen.
+type voiceDetector struct {
+	threshold  float64
+	sampleRate int
+	hangover   time.Duration
+	minLen     time.Duration
+	maxLen     time.Duration
+
+	utterance []byte
+	inSpeech  bool
+	silence   int // байт тишины в конце текущей фразы
+}
+
+func newVoiceDetector(threshold float64, sampleRate int, hangover, minLen, maxLen time.Duration) *voiceDetector {
+	if threshold <= 0 {
+		threshold = defaultSilenceThreshold
+	}
+	if hangover <= 0 {
+		hangover = defaultVADHangover
+	}
+	if minLen <= 0 {
+		minLen = defaultMinUtterance
+	}
+	if maxLen <= 0 {
+		maxLen = defaultMaxUtterance
+	}
+
+	return &voiceDetector{
+		threshold:  threshold,
+		sampleRate: sampleRate,
+		hangover:   hangover,
+		minLen:     minLen,
+		maxLen:     maxLen,
+	}
+}
+
+// Push обрабатывает очередной фрейм. voiced сообщает, относится ли фрейм к фразе
+// (речь или тишина внутри hangover). Если фраза завершилась на этом фрейме, ended = true,
+// а utterance содержит ее аудио либо nil, если фраза короче minLen.
+func (v *voiceDetector) Push(frame []byte) (utterance []byte, voiced bool, ended bool) {
+	speech := frameRMS(frame) >= v.threshold
+
+	if !v.inSpeech {
+		if !speech {
+			return nil, false, false
+		}
+		v.inSpeech = true
+		v.silence = 0
+		v.utterance = v.utterance[:0]
+	}
+
+	v.utterance = append(v.utterance, frame...)
+	if speech {
+		v.silence = 0
+	} else {
+		v.silence += len(frame)
+	}
+
+	if v.duration(v.silence) > v.hangover || v.duration(len(v.utterance)) >= v.maxLen {
+		return v.finish(), true, true
+	}
+
+	return nil, true, false
+}
+
+// Flush завершает незаконченную фразу, например в конце потока
+func (v *voiceDetector) Flush() (utterance []byte, ended bool) {
+	if !v.inSpeech {
+		return nil, false
+	}
+	return v.finish(), true
+}
+
+func (v *voiceDetector) finish() []byte {
+	v.inSpeech = false
+
+	// Хвост тишины не нужен транскриберу
+	speech := v.utterance[:len(v.utterance)-v.silence]
+	if v.duration(len(speech)) < v.minLen {
+		return nil
+	}
+
+	utterance := make([]byte, len(speech))
+	copy(utterance, speech)
+	return utterance
+}
+
+func (v *voiceDetector) duration(bytes int) time.Duration {
+	samples := bytes / pcmBytesPerSample
+	return time.Duration(samples) * time.Second / time.Duration(v.sampleRate)
+}
+
+// frameRMS возвращает среднеквадратичную амплитуду фрейма 16-битного PCM в диапазоне 0..1
+func frameRMS(frame []byte) float64 {
+	samples := len(frame) / pcmBytesPerSample
+	if samples == 0 {
+		return 0
+	}
+
+	var sum float64
+	for i := 0; i < samples; i++ {
+		sample := float64(int16(binary.LittleEndian.Uint16(frame[i*pcmBytesPerSample:]))) / 32768
+		sum += sample * sample
+	}
+	return math.Sqrt(sum / float64(samples))
+}
@@ -0,0 +1,177 @@
+package audio
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const (
+	testVADRate    = 16000
+	testVADFrameMs = 20
+)
+
+// vadSegment - отрезок сигнала: речь (громкий тон) или тишина заданной длительности
+type vadSegment struct {
+	ms     int
+	speech bool
+}
+
+// vadFrames нарезает отрезки на фреймы по 20 мс 16-битного PCM
+func vadFrames(segments []vadSegment) [][]byte {
+	var frames [][]byte
+	for _, segment := range segments {
+		amplitude := int16(0)
+		if segment.speech {
+			amplitude = 8000 // RMS около 0.24, выше порога по умолчанию
+		}
+		for ms := 0; ms < segment.ms; ms += testVADFrameMs {
+			frame := make([]byte, testVADRate*testVADFrameMs/1000*pcmBytesPerSample)
+			for i := 0; i < len(frame); i += pcmBytesPerSample {
+				sample := amplitude
+				if (i/pcmBytesPerSample)%2 == 1 {
+					sample = -amplitude
+				}
+				binary.LittleEndian.PutUint16(frame[i:], uint16(sample))
+			}
+			frames = append(frames, frame)
+		}
+	}
+	return frames
+}
+
+// runVAD прогоняет фреймы через детектор и возвращает длительности завершенных фраз
+// (0 - фраза отброшена как слишком короткая) и номера фреймов, на которых они завершились.
+// Незаконченная фраза завершается через Flush, ее фрейм -1.
+func runVAD(v *voiceDetector, frames [][]byte) (durations []time.Duration, endFrames []int) {
+	record := func(utterance []byte, frame int) {
+		durations = append(durations, v.duration(len(utterance)))
+		endFrames = append(endFrames, frame)
+	}
+	for i, frame := range frames {
+		if utterance, _, ended := v.Push(frame); ended {
+			record(utterance, i)
+		}
+	}
+	if utterance, ended := v.Flush(); ended {
+		record(utterance, -1)
+	}
+	return durations, endFrames
+}
+
+func TestVoiceDetectorSequences(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name      string
+		maxLen    time.Duration
+		segments  []vadSegment
+		durations []time.Duration
+		endFrames []int
+	}{
+		{
+			name:     "silence only",
+			segments: []vadSegment{{ms: 1000}},
+		},
+		{
+			// Тишина дольше hangover (500 мс) завершает фразу на 26-м фрейме тишины, хвост отрезается
+			name:      "hangover ends utterance",
+			segments:  []vadSegment{{ms: 200}, {ms: 600, speech: true}, {ms: 700}},
+			durations: []time.Duration{600 * ms},
+			endFrames: []int{10 + 30 + 25},
+		},
+		{
+			// Пауза короче hangover остается внутри фразы
+			name:      "pause within hangover",
+			segments:  []vadSegment{{ms: 400, speech: true}, {ms: 400}, {ms: 400, speech: true}, {ms: 600}},
+			durations: []time.Duration{1200 * ms},
+			endFrames: []int{20 + 20 + 20 + 25},
+		},
+		{
+			name:      "two utterances",
+			segments:  []vadSegment{{ms: 400, speech: true}, {ms: 600}, {ms: 800, speech: true}, {ms: 600}},
+			durations: []time.Duration{400 * ms, 800 * ms},
+			endFrames: []int{20 + 25, 20 + 30 + 40 + 25},
+		},
+		{
+			// Фраза короче vad_min_utterance_ms (300 мс) отбрасывается, следующая - нет
+			name:      "short utterance dropped",
+			segments:  []vadSegment{{ms: 200, speech: true}, {ms: 600}, {ms: 300, speech: true}, {ms: 600}},
+			durations: []time.Duration{0, 300 * ms},
+			endFrames: []int{10 + 25, 10 + 30 + 15 + 25},
+		},
+		{
+			// Длинная речь режется по vad_max_utterance_ms, остаток завершает Flush
+			name:      "max utterance split",
+			maxLen:    time.Second,
+			segments:  []vadSegment{{ms: 2500, speech: true}},
+			durations: []time.Duration{time.Second, time.Second, 500 * ms},
+			endFrames: []int{49, 99, -1},
+		},
+		{
+			// Остаток после разреза короче minLen отбрасывается
+			name:      "short tail after split",
+			maxLen:    time.Second,
+			segments:  []vadSegment{{ms: 1200, speech: true}, {ms: 600}},
+			durations: []time.Duration{time.Second, 0},
+			endFrames: []int{49, 60 + 25},
+		},
+		{
+			name:      "flush unfinished utterance",
+			segments:  []vadSegment{{ms: 400, speech: true}, {ms: 200}},
+			durations: []time.Duration{400 * ms},
+			endFrames: []int{-1},
+		},
+		{
+			name:      "flush short utterance",
+			segments:  []vadSegment{{ms: 100, speech: true}},
+			durations: []time.Duration{0},
+			endFrames: []int{-1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newVoiceDetector(0, testVADRate, 0, 0, tt.maxLen)
+			durations, endFrames := runVAD(v, vadFrames(tt.segments))
+			if !reflect.DeepEqual(durations, tt.durations) {
+				t.Errorf("utterances = %v, want %v", durations, tt.durations)
+			}
+			if !reflect.DeepEqual(endFrames, tt.endFrames) {
+				t.Errorf("ended on frames %v, want %v", endFrames, tt.endFrames)
+			}
+		})
+	}
+}
+
+func TestVoiceDetectorVoicedFrames(t *testing.T) {
+	v := newVoiceDetector(0, testVADRate, 100*time.Millisecond, 0, 0)
+	frames := vadFrames([]vadSegment{{ms: 40}, {ms: 60, speech: true}, {ms: 140}, {ms: 40}})
+
+	// Тишина до речи не относится к фразе, тишина внутри hangover и фрейм,
+	// завершивший фразу (шестой фрейм тишины, 120 мс), - относятся
+	want := []bool{false, false, true, true, true, true, true, true, true, true, true, false, false, false}
+	for i, frame := range frames {
+		_, voiced, ended := v.Push(frame)
+		if voiced != want[i] {
+			t.Errorf("frame %d: voiced = %v, want %v", i, voiced, want[i])
+		}
+		if ended != (i == 10) {
+			t.Errorf("frame %d: ended = %v", i, ended)
+		}
+	}
+}
+
+func TestVoiceDetectorThreshold(t *testing.T) {
+	quiet := make([]byte, 640)
+	for i := 0; i < len(quiet); i += pcmBytesPerSample {
+		binary.LittleEndian.PutUint16(quiet[i:], uint16(int16(300))) // RMS около 0.009
+	}
+
+	if _, voiced, _ := newVoiceDetector(0, testVADRate, 0, 0, 0).Push(quiet); voiced {
+		t.Error("background noise below the default threshold counted as speech")
+	}
+	if _, voiced, _ := newVoiceDetector(0.005, testVADRate, 0, 0, 0).Push(quiet); !voiced {
+		t.Error("frame above a lowered threshold not counted as speech")
+	}
+}
@@ -0,0 +1,190 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// VoskTranscriber стримит PCM на vosk-server (https://github.com/alphacep/vosk-server) по WebSocket.
+// Сервер отвечает на каждый фрагмент аудио либо промежуточным результатом ({"partial": ...}),
+// либо финальным ({"text": ...}) на границе фразы.
+//
+// Параметры из transcriber_config:
+//   - url: адрес сервера, по умолчанию ws://localhost:2700
+//   - sample_rate: частота дискретизации PCM, подставляется модулем из audio.sample_rate
+type VoskTranscriber struct {
+	url        string
+	sampleRate int
+	conn       *websocket.Conn
+	partials   chan string
+	lastPart   string
+	mu         sync.Mutex
+}
+
+func NewVoskTranscriber(config map[string]string) *VoskTranscriber {
+	url := config["url"]
+	if url == "" {
+		url = "ws://localhost:2700"
+	}
+	sampleRate, err := strconv.Atoi(config["sample_rate"])
+	if err != nil || sampleRate <= 0 {
+		sampleRate = 16000
+	}
+
+	return &VoskTranscriber{
+		url:        url,
+		sampleRate: sampleRate,
+		partials:   make(chan string, 10),
+	}
+}
+
+type voskResult struct {
+	Partial string `json:"partial"`
+	Text    string `json:"text"`
+}
+
+func (v *VoskTranscriber) Initialize() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if err := v.connect(); err != nil {
+		return err
+	}
+
+	log.Printf("🎤 VoskTranscriber initialized (server: %s, sample rate: %d)", v.url, v.sampleRate)
+	return nil
+}
+
+// connect открывает соединение и передает серверу параметры аудиопотока
+func (v *VoskTranscriber) connect() error {
+	dialer := websocket.Dialer{HandshakeTimeout: 5 * time.Second}
+	conn, _, err := dialer.Dial(v.url, nil)
+	if err != nil {
+		return fmt.Errorf("vosk connect failed: %w", err)
+	}
+
+	config := map[string]interface{}{
+		"config": map[string]interface{}{"sample_rate": v.sampleRate},
+	}
+	if err := conn.WriteJSON(config); err != nil {
+		conn.Close()
+		return fmt.Errorf("vosk config failed: %w", err)
+	}
+
+	v.conn = conn
+	v.lastPart = ""
+	return nil
+}
+
+// Transcribe отправляет фрагмент PCM и возвращает текст, только когда сервер завершил фразу.
+// Промежуточные результаты уходят в канал Partials.
+func (v *VoskTranscriber) Transcribe(ctx context.Context, audioData []byte) (string, error) {
+	if len(audioData) == 0 {
+		return "", nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.conn == nil {
+		if err := v.connect(); err != nil {
+			return "", err
+		}
+	}
+
+	v.setDeadline(ctx)
+
+	if err := v.conn.WriteMessage(websocket.BinaryMessage, audioData); err != nil {
+		v.dropConn()
+		return "", fmt.Errorf("vosk send failed: %w", err)
+	}
+
+	var result voskResult
+	if err := v.conn.ReadJSON(&result); err != nil {
+		v.dropConn()
+		return "", fmt.Errorf("vosk receive failed: %w", err)
+	}
+
+	if text := strings.TrimSpace(result.Text); text != "" {
+		v.lastPart = ""
+		return text, nil
+	}
+
+	if partial := strings.TrimSpace(result.Partial); partial != "" && partial != v.lastPart {
+		v.lastPart = partial
+		select {
+		case v.partials <- partial:
+		default:
+			// UI не успевает - промежуточный результат можно потерять
+		}
+	}
+
+	return "", nil
+}
+
+// FinishUtterance запрашивает у сервера финальный результат по текущей фразе.
+// Протокол vosk-server завершает сессию после eof, поэтому соединение переоткрывается при следующем Transcribe.
+func (v *VoskTranscriber) FinishUtterance(ctx context.Context) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.conn == nil {
+		return "", nil
+	}
+	defer v.dropConn()
+
+	v.setDeadline(ctx)
+	if err := v.conn.WriteMessage(websocket.TextMessage, []byte(`{"eof" : 1}`)); err != nil {
+		return "", fmt.Errorf("vosk eof failed: %w", err)
+	}
+
+	var result voskResult
+	if err := v.conn.ReadJSON(&result); err != nil {
+		return "", fmt.Errorf("vosk final result failed: %w", err)
+	}
+
+	return strings.TrimSpace(result.Text), nil
+}
+
+func (v *VoskTranscriber) setDeadline(ctx context.Context) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(10 * time.Second)
+	}
+	v.conn.SetWriteDeadline(deadline)
+	v.conn.SetReadDeadline(deadline)
+}
+
+// Partials возвращает канал промежуточных результатов распознавания
+func (v *VoskTranscriber) Partials() <-chan string {
+	return v.partials
+}
+
+// dropConn закрывает сломанное соединение; следующий Transcribe переподключится
+func (v *VoskTranscriber) dropConn() {
+	if v.conn != nil {
+		v.conn.Close()
+		v.conn = nil
+	}
+}
+
+func (v *VoskTranscriber) Close() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.conn != nil {
+		v.conn.WriteMessage(websocket.TextMessage, []byte(`{"eof" : 1}`))
+		v.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		v.dropConn()
+	}
+
+	log.Println("🎤 VoskTranscriber closed")
+	return nil
+}
@@ -0,0 +1,309 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// voskStub - заглушка vosk-server: на каждый фрагмент аудио отвечает очередным ответом из replies
+// (пустой partial, если они кончились), на eof - финальным результатом final.
+// Запоминает параметры сессий и полученное аудио.
+type voskStub struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	replies  []string
+	final    string
+	sessions []int    // sample_rate из конфига каждой сессии
+	audio    [][]byte // полученные фрагменты
+	eofs     int
+	silent   bool // не отвечать на аудио
+	dropNext bool // закрыть соединение на следующем фрагменте
+}
+
+func newVoskStub(t *testing.T, replies ...string) *voskStub {
+	t.Helper()
+	stub := &voskStub{replies: replies}
+	upgrader := websocket.Upgrader{}
+	stub.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		var config struct {
+			Config struct {
+				SampleRate int `json:"sample_rate"`
+			} `json:"config"`
+		}
+		if err := conn.ReadJSON(&config); err != nil {
+			t.Errorf("read config: %v", err)
+			return
+		}
+		stub.mu.Lock()
+		stub.sessions = append(stub.sessions, config.Config.SampleRate)
+		stub.mu.Unlock()
+
+		for {
+			kind, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if reply, ok := stub.reply(kind, data); ok {
+				conn.WriteMessage(websocket.TextMessage, []byte(reply))
+			} else if kind == websocket.BinaryMessage && stub.dropped() {
+				return
+			}
+		}
+	}))
+	t.Cleanup(stub.server.Close)
+	return stub
+}
+
+// reply возвращает ответ сервера на сообщение клиента; ok = false - ответа нет
+func (s *voskStub) reply(kind int, data []byte) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if kind == websocket.TextMessage {
+		if string(data) != `{"eof" : 1}` {
+			return "", false
+		}
+		s.eofs++
+		return s.final, true
+	}
+
+	s.audio = append(s.audio, append([]byte(nil), data...))
+	if s.silent || s.dropNext {
+		return "", false
+	}
+	if len(s.replies) == 0 {
+		return `{"partial" : ""}`, true
+	}
+	reply := s.replies[0]
+	s.replies = s.replies[1:]
+	return reply, true
+}
+
+func (s *voskStub) dropped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	drop := s.dropNext
+	s.dropNext = false
+	return drop
+}
+
+func (s *voskStub) snapshot() (sessions []int, audio [][]byte, eofs int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int(nil), s.sessions...), append([][]byte(nil), s.audio...), s.eofs
+}
+
+func newTestVosk(t *testing.T, stub *voskStub, extra map[string]string) *VoskTranscriber {
+	t.Helper()
+	cfg := map[string]string{
+		"url":         "ws" + strings.TrimPrefix(stub.server.URL, "http"),
+		"sample_rate": "8000",
+	}
+	for key, value := range extra {
+		cfg[key] = value
+	}
+
+	transcriber, err := NewTranscriber("vosk", cfg)
+	if err != nil {
+		t.Fatalf("NewTranscriber: %v", err)
+	}
+	if err := transcriber.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	t.Cleanup(func() { transcriber.Close() })
+	return transcriber.(*VoskTranscriber)
+}
+
+// drainPartials забирает промежуточные результаты, накопившиеся в канале
+func drainPartials(v *VoskTranscriber) []string {
+	var partials []string
+	for {
+		select {
+		case partial := <-v.Partials():
+			partials = append(partials, partial)
+		default:
+			return partials
+		}
+	}
+}
+
+func TestVoskStreamsAudioWithConfiguredSampleRate(t *testing.T) {
+	stub := newVoskStub(t, `{"partial" : "покажи"}`, `{"text" : " покажи логи "}`)
+	v := newTestVosk(t, stub, nil)
+
+	pcm := []byte{1, 0, 2, 0}
+	for i, want := range []string{"", "покажи логи"} {
+		text, err := v.Transcribe(context.Background(), pcm)
+		if err != nil {
+			t.Fatalf("Transcribe %d: %v", i, err)
+		}
+		if text != want {
+			t.Errorf("Transcribe %d = %q, want %q", i, text, want)
+		}
+	}
+
+	// Пустой фрагмент на сервер не уходит
+	if text, err := v.Transcribe(context.Background(), nil); text != "" || err != nil {
+		t.Errorf("empty Transcribe = %q, %v", text, err)
+	}
+
+	sessions, audio, _ := stub.snapshot()
+	if len(sessions) != 1 || sessions[0] != 8000 {
+		t.Errorf("sessions = %v, want one with sample rate 8000", sessions)
+	}
+	if len(audio) != 2 || !bytes.Equal(audio[0], pcm) {
+		t.Errorf("server received %d fragments: %v", len(audio), audio)
+	}
+}
+
+func TestVoskDefaultsWithoutConfig(t *testing.T) {
+	v := NewVoskTranscriber(map[string]string{"sample_rate": "bad"})
+	if v.url != "ws://localhost:2700" || v.sampleRate != 16000 {
+		t.Errorf("url = %q, sample rate = %d", v.url, v.sampleRate)
+	}
+}
+
+func TestVoskPublishesChangedPartials(t *testing.T) {
+	stub := newVoskStub(t,
+		`{"partial" : "покажи"}`,
+		`{"partial" : "покажи "}`, // тот же текст после обрезки пробелов
+		`{"partial" : "покажи логи"}`,
+		`{"text" : "покажи логи"}`,
+		`{"partial" : "покажи"}`, // после финала тот же partial публикуется снова
+	)
+	v := newTestVosk(t, stub, nil)
+
+	for i := 0; i < 5; i++ {
+		if _, err := v.Transcribe(context.Background(), []byte{0, 0}); err != nil {
+			t.Fatalf("Transcribe %d: %v", i, err)
+		}
+	}
+
+	partials := drainPartials(v)
+	want := []string{"покажи", "покажи логи", "покажи"}
+	if strings.Join(partials, "|") != strings.Join(want, "|") {
+		t.Errorf("partials = %q, want %q", partials, want)
+	}
+}
+
+func TestVoskDropsPartialsWhenNobodyReads(t *testing.T) {
+	var replies []string
+	for i := 0; i < 15; i++ {
+		data, _ := json.Marshal(voskResult{Partial: strings.Repeat("а", i+1)})
+		replies = append(replies, string(data))
+	}
+	v := newTestVosk(t, newVoskStub(t, replies...), nil)
+
+	for i := range replies {
+		if _, err := v.Transcribe(context.Background(), []byte{0, 0}); err != nil {
+			t.Fatalf("Transcribe %d: %v", i, err)
+		}
+	}
+	if partials := drainPartials(v); len(partials) != cap(v.partials) {
+		t.Errorf("buffered %d partials, want the channel capacity %d", len(partials), cap(v.partials))
+	}
+}
+
+func TestVoskFinishUtteranceReconnects(t *testing.T) {
+	stub := newVoskStub(t, `{"partial" : "покажи"}`)
+	stub.final = `{"text" : " покажи логи "}`
+	v := newTestVosk(t, stub, nil)
+
+	if _, err := v.Transcribe(context.Background(), []byte{0, 0}); err != nil {
+		t.Fatalf("Transcribe: %v", err)
+	}
+	text, err := v.FinishUtterance(context.Background())
+	if err != nil || text != "покажи логи" {
+		t.Fatalf("FinishUtterance = %q, %v", text, err)
+	}
+
+	// После eof сервер закрывает сессию: следующий фрагмент идет в новой
+	if _, err := v.Transcribe(context.Background(), []byte{0, 0}); err != nil {
+		t.Fatalf("Transcribe after eof: %v", err)
+	}
+	sessions, audio, eofs := stub.snapshot()
+	if len(sessions) != 2 || sessions[1] != 8000 || eofs != 1 || len(audio) != 2 {
+		t.Errorf("sessions %v, eofs %d, fragments %d; want a second configured session", sessions, eofs, len(audio))
+	}
+
+	// Без открытой сессии завершать нечего
+	v.FinishUtterance(context.Background())
+	if text, err := v.FinishUtterance(context.Background()); text != "" || err != nil {
+		t.Errorf("FinishUtterance without session = %q, %v", text, err)
+	}
+	if _, _, eofs := stub.snapshot(); eofs != 2 {
+		t.Errorf("eofs = %d, want 2", eofs)
+	}
+}
+
+func TestVoskReconnectsAfterBrokenConnection(t *testing.T) {
+	stub := newVoskStub(t)
+	v := newTestVosk(t, stub, nil)
+
+	stub.mu.Lock()
+	stub.dropNext = true
+	stub.mu.Unlock()
+	if _, err := v.Transcribe(context.Background(), []byte{0, 0}); err == nil || !strings.Contains(err.Error(), "vosk receive failed") {
+		t.Fatalf("Transcribe on dropped connection = %v", err)
+	}
+
+	stub.mu.Lock()
+	stub.replies = []string{`{"text" : "снова на связи"}`}
+	stub.mu.Unlock()
+	text, err := v.Transcribe(context.Background(), []byte{0, 0})
+	if err != nil || text != "снова на связи" {
+		t.Fatalf("Transcribe after reconnect = %q, %v", text, err)
+	}
+	if sessions, _, _ := stub.snapshot(); len(sessions) != 2 {
+		t.Errorf("sessions = %v, want a reconnect", sessions)
+	}
+}
+
+func TestVoskTranscribeHonorsContextDeadline(t *testing.T) {
+	stub := newVoskStub(t)
+	stub.silent = true
+	v := newTestVosk(t, stub, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := v.Transcribe(ctx, []byte{0, 0})
+	var netErr interface{ Timeout() bool }
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("error = %v, want a read timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Transcribe took %v, want the context deadline", elapsed)
+	}
+}
+
+func TestVoskInitializeFailsWithoutServer(t *testing.T) {
+	stub := newVoskStub(t)
+	stub.server.Close()
+
+	transcriber, err := NewTranscriber("vosk", map[string]string{"url": "ws" + strings.TrimPrefix(stub.server.URL, "http")})
+	if err != nil {
+		t.Fatalf("NewTranscriber: %v", err)
+	}
+	if err := transcriber.Initialize(); err == nil || !strings.Contains(err.Error(), "vosk connect failed") {
+		t.Errorf("Initialize = %v, want a connect error", err)
+	}
+}
@@ -0,0 +1,100 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	pcmChannels       = 1
+	pcmBitsPerSample  = 16
+	pcmBytesPerSample = pcmBitsPerSample / 8
+)
+
+// encodeWAV оборачивает сырой PCM (16 бит, моно, little-endian) в WAV контейнер
+func encodeWAV(pcm []byte, sampleRate int) []byte {
+	byteRate := sampleRate * pcmChannels * pcmBitsPerSample / 8
+	blockAlign := pcmChannels * pcmBitsPerSample / 8
+
+	var buf bytes.Buffer
+	buf.Grow(44 + len(pcm))
+
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+len(pcm)))
+	buf.WriteString("WAVE")
+
+	buf.WriteString("fmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, uint16(1)) // PCM
+	binary.Write(&buf, binary.LittleEndian, uint16(pcmChannels))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&buf, binary.LittleEndian, uint32(byteRate))
+	binary.Write(&buf, binary.LittleEndian, uint16(blockAlign))
+	binary.Write(&buf, binary.LittleEndian, uint16(pcmBitsPerSample))
+
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(pcm)))
+	buf.Write(pcm)
+
+	return buf.Bytes()
+}
+
+// wavFormat - параметры аудио из заголовка WAV файла
+type wavFormat struct {
+	AudioFormat   uint16
+	Channels      uint16
+	SampleRate    uint32
+	ByteRate      uint32
+	BlockAlign    uint16
+	BitsPerSample uint16
+}
+
+// readWAVHeader разбирает RIFF заголовок и оставляет r в начале блока data.
+// Возвращает формат и размер данных в байтах.
+func readWAVHeader(r io.Reader) (wavFormat, int64, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return wavFormat{}, 0, fmt.Errorf("read RIFF header: %w", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return wavFormat{}, 0, errors.New("not a RIFF/WAVE file")
+	}
+
+	var format wavFormat
+	haveFormat := false
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return wavFormat{}, 0, fmt.Errorf("read chunk header: %w", err)
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return wavFormat{}, 0, fmt.Errorf("fmt chunk too short: %d bytes", size)
+			}
+			if err := binary.Read(r, binary.LittleEndian, &format); err != nil {
+				return wavFormat{}, 0, fmt.Errorf("read fmt chunk: %w", err)
+			}
+			if _, err := io.CopyN(io.Discard, r, size-16+size%2); err != nil {
+				return wavFormat{}, 0, err
+			}
+			haveFormat = true
+		case "data":
+			if !haveFormat {
+				return wavFormat{}, 0, errors.New("data chunk before fmt chunk")
+			}
+			return format, size, nil
+		default:
+			// Пропускаем LIST, fact и прочие служебные блоки (с выравниванием по 2 байта)
+			if _, err := io.CopyN(io.Discard, r, size+size%2); err != nil {
+				return wavFormat{}, 0, fmt.Errorf("skip %q chunk: %w", id, err)
+			}
+		}
+	}
+}
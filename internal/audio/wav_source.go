@@ -0,0 +1,124 @@
+package audio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// WAVFileSource читает PCM из WAV файла с той скоростью, с какой звук шел бы в реальном времени,
+// чтобы запись совещания обрабатывалась так же, как живой поток.
+// Open и Read вызываются из горутины захвата, Close - из Stop параллельно с ними.
+type WAVFileSource struct {
+	path       string
+	sampleRate int
+
+	mu     sync.Mutex // защищает file, data и closed
+	file   *os.File
+	data   io.Reader
+	closed bool
+
+	// Используются только горутиной захвата
+	started   time.Time
+	delivered int64
+}
+
+func NewWAVFileSource(path string, sampleRate int) *WAVFileSource {
+	return &WAVFileSource{
+		path:       path,
+		sampleRate: sampleRate,
+	}
+}
+
+func (w *WAVFileSource) Open() error {
+	w.mu.Lock()
+	closed := w.closed
+	w.mu.Unlock()
+	if closed {
+		return errSourceClosed
+	}
+
+	file, err := os.Open(w.path)
+	if err != nil {
+		return fmt.Errorf("open wav source: %w", err)
+	}
+
+	reader := bufio.NewReader(file)
+	format, size, err := readWAVHeader(reader)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("%s: %w", w.path, err)
+	}
+
+	if format.AudioFormat != 1 || format.BitsPerSample != pcmBitsPerSample || format.Channels != pcmChannels {
+		file.Close()
+		return fmt.Errorf("%s: expected 16-bit mono PCM, got format=%d bits=%d channels=%d",
+			w.path, format.AudioFormat, format.BitsPerSample, format.Channels)
+	}
+	if int(format.SampleRate) != w.sampleRate {
+		file.Close()
+		return fmt.Errorf("%s: sample rate %d does not match audio.sample_rate %d", w.path, format.SampleRate, w.sampleRate)
+	}
+
+	var data io.Reader = reader
+	// Потоковые WAV часто пишут размер 0 или 0xFFFFFFFF - тогда читаем до конца файла
+	if size > 0 && size < 0xFFFFFFFF {
+		data = io.LimitReader(reader, size)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		file.Close()
+		return errSourceClosed
+	}
+	w.file = file
+	w.data = data
+	w.started = time.Now()
+	w.delivered = 0
+
+	log.Printf("🎙️  WAV source opened: %s", w.path)
+	return nil
+}
+
+// Read отдает данные не быстрее реального времени воспроизведения
+func (w *WAVFileSource) Read(p []byte) (int, error) {
+	w.mu.Lock()
+	data := w.data
+	w.mu.Unlock()
+
+	if data == nil {
+		return 0, io.EOF
+	}
+
+	n, err := data.Read(p)
+	w.delivered += int64(n)
+
+	byteRate := int64(w.sampleRate * pcmBytesPerSample)
+	due := w.started.Add(time.Duration(w.delivered * int64(time.Second) / byteRate))
+	if wait := time.Until(due); wait > 0 {
+		time.Sleep(wait)
+	}
+
+	return n, err
+}
+
+// Close может вызываться параллельно с Open и Read: чтение закрытого файла вернет ошибку,
+// а незавершенный Open закроет файл сам
+func (w *WAVFileSource) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Close()
+}
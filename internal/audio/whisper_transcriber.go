@@ -0,0 +1,261 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WhisperTranscriber отправляет PCM фрагменты на HTTP сервер whisper.cpp (examples/server)
+//
+// Параметры из transcriber_config:
+//   - url: адрес сервера, по умолчанию http://localhost:8081
+//   - language: язык речи ("ru", "en", "auto"), по умолчанию "ru"
+//   - prompt: начальный промпт для модели (термины, имена сервисов)
+//   - diarize: "true" включает tinydiarize (нужна модель *.tdrz); whisper отмечает только смену
+//     говорящего, поэтому реплики помечаются попеременно как S1 и S2
+//   - sample_rate: частота дискретизации PCM, подставляется модулем из audio.sample_rate
+type WhisperTranscriber struct {
+	endpoint   string
+	language   string
+	prompt     string
+	diarize    bool
+	sampleRate int
+	client     *http.Client
+	speaker    int // номер текущего говорящего при диаризации, сохраняется между фрагментами
+}
+
+func NewWhisperTranscriber(config map[string]string) *WhisperTranscriber {
+	baseURL := config["url"]
+	if baseURL == "" {
+		baseURL = "http://localhost:8081"
+	}
+	language := config["language"]
+	if language == "" {
+		language = "ru"
+	}
+	sampleRate, err := strconv.Atoi(config["sample_rate"])
+	if err != nil || sampleRate <= 0 {
+		sampleRate = 16000
+	}
+
+	return &WhisperTranscriber{
+		endpoint:   strings.TrimRight(baseURL, "/") + "/inference",
+		language:   language,
+		prompt:     config["prompt"],
+		diarize:    config["diarize"] == "true",
+		sampleRate: sampleRate,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// speakerTurnMarker - маркер смены говорящего в тексте при включенном tinydiarize
+const speakerTurnMarker = "[SPEAKER_TURN]"
+
+type whisperSegment struct {
+	Text            string   `json:"text"`
+	Start           float64  `json:"start"`
+	End             float64  `json:"end"`
+	AvgLogprob      *float64 `json:"avg_logprob"`
+	SpeakerTurnNext bool     `json:"speaker_turn_next"`
+}
+
+type whisperResponse struct {
+	Text     string           `json:"text"`
+	Language string           `json:"language"`
+	Segments []whisperSegment `json:"segments"`
+	Error    string           `json:"error,omitempty"`
+}
+
+func (w *WhisperTranscriber) Initialize() error {
+	if _, err := url.ParseRequestURI(w.endpoint); err != nil {
+		return fmt.Errorf("invalid whisper url: %w", err)
+	}
+
+	log.Printf("🎤 WhisperTranscriber initialized (endpoint: %s, language: %s)", w.endpoint, w.language)
+	return nil
+}
+
+func (w *WhisperTranscriber) Transcribe(ctx context.Context, audioData []byte) (string, error) {
+	turns, err := w.TranscribeTurns(ctx, audioData)
+	if err != nil {
+		return "", err
+	}
+
+	texts := make([]string, 0, len(turns))
+	for _, turn := range turns {
+		texts = append(texts, turn.Text)
+	}
+	return strings.Join(texts, " "), nil
+}
+
+// TranscribeTurns распознает фрагмент и группирует сегменты whisper в реплики.
+// Без диаризации весь фрагмент - одна реплика без метки говорящего.
+func (w *WhisperTranscriber) TranscribeTurns(ctx context.Context, audioData []byte) ([]SpeakerTurn, error) {
+	if len(audioData) == 0 {
+		return nil, nil
+	}
+
+	resp, err := w.inference(ctx, audioData)
+	if err != nil {
+		return nil, err
+	}
+
+	language := w.language
+	if resp.Language != "" {
+		language = resp.Language
+	}
+
+	// Старые версии сервера не отдают сегменты - используем общий текст
+	if len(resp.Segments) == 0 {
+		text := strings.TrimSpace(strings.ReplaceAll(resp.Text, speakerTurnMarker, ""))
+		if text == "" {
+			return nil, nil
+		}
+		return []SpeakerTurn{{Text: text, Speaker: w.speakerLabel(), Language: language}}, nil
+	}
+
+	var turns []SpeakerTurn
+	var current *SpeakerTurn
+	var logprobSum float64
+	var segments int
+
+	flush := func() {
+		if current != nil && strings.TrimSpace(current.Text) != "" {
+			current.Text = strings.TrimSpace(current.Text)
+			if segments > 0 {
+				current.Confidence = math.Exp(logprobSum / float64(segments))
+			}
+			turns = append(turns, *current)
+		}
+		current = nil
+		logprobSum, segments = 0, 0
+	}
+
+	for _, segment := range resp.Segments {
+		if current == nil {
+			current = &SpeakerTurn{
+				Speaker:  w.speakerLabel(),
+				Offset:   secondsToDuration(segment.Start),
+				Language: language,
+			}
+		}
+
+		turnNext := segment.SpeakerTurnNext || strings.Contains(segment.Text, speakerTurnMarker)
+		text := strings.TrimSpace(strings.ReplaceAll(segment.Text, speakerTurnMarker, ""))
+		current.Text = strings.TrimSpace(current.Text + " " + text)
+		current.Duration = secondsToDuration(segment.End) - current.Offset
+		if segment.AvgLogprob != nil {
+			logprobSum += *segment.AvgLogprob
+			segments++
+		}
+
+		if turnNext && w.diarize {
+			flush()
+			w.speaker = (w.speaker + 1) % 2
+		}
+	}
+	flush()
+
+	return turns, nil
+}
+
+func (w *WhisperTranscriber) speakerLabel() string {
+	if !w.diarize {
+		return ""
+	}
+	return fmt.Sprintf("S%d", w.speaker+1)
+}
+
+// inference отправляет фрагмент на /inference и возвращает подробный ответ с сегментами
+func (w *WhisperTranscriber) inference(ctx context.Context, audioData []byte) (whisperResponse, error) {
+	body, contentType, err := w.buildForm(audioData)
+	if err != nil {
+		return whisperResponse{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", w.endpoint, body)
+	if err != nil {
+		return whisperResponse{}, err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return whisperResponse{}, fmt.Errorf("whisper request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return whisperResponse{}, fmt.Errorf("whisper returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var whisperResp whisperResponse
+	if err := json.NewDecoder(resp.Body).Decode(&whisperResp); err != nil {
+		return whisperResponse{}, fmt.Errorf("whisper response decode failed: %w", err)
+	}
+	if whisperResp.Error != "" {
+		return whisperResponse{}, fmt.Errorf("whisper error: %s", whisperResp.Error)
+	}
+
+	return whisperResp, nil
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// buildForm собирает multipart запрос в формате /inference: WAV файл и параметры распознавания
+func (w *WhisperTranscriber) buildForm(audioData []byte) (*bytes.Buffer, string, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+
+	file, err := form.CreateFormFile("file", "audio.wav")
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := file.Write(encodeWAV(audioData, w.sampleRate)); err != nil {
+		return nil, "", err
+	}
+
+	fields := map[string]string{
+		"response_format": "verbose_json",
+		"temperature":     "0.0",
+		"language":        w.language,
+	}
+	if w.prompt != "" {
+		fields["prompt"] = w.prompt
+	}
+	if w.diarize {
+		fields["tinydiarize"] = "true"
+	}
+	for name, value := range fields {
+		if err := form.WriteField(name, value); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := form.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return &body, form.FormDataContentType(), nil
+}
+
+func (w *WhisperTranscriber) Close() error {
+	log.Println("🎤 WhisperTranscriber closed")
+	return nil
+}
@@ -0,0 +1,253 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// whisperStub - заглушка /inference сервера whisper.cpp, запоминающая последний запрос
+type whisperStub struct {
+	server   *httptest.Server
+	fields   map[string]string
+	wav      []byte
+	response string
+	status   int
+}
+
+func newWhisperStub(t *testing.T, response string) *whisperStub {
+	t.Helper()
+	stub := &whisperStub{response: response, status: http.StatusOK}
+	stub.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/inference" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("parse multipart form: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		stub.fields = make(map[string]string)
+		for name, values := range r.MultipartForm.Value {
+			stub.fields[name] = values[0]
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("form file: %v", err)
+		} else {
+			if header.Filename != "audio.wav" {
+				t.Errorf("file name = %q, want audio.wav", header.Filename)
+			}
+			stub.wav, _ = io.ReadAll(file)
+			file.Close()
+		}
+
+		w.WriteHeader(stub.status)
+		io.WriteString(w, stub.response)
+	}))
+	t.Cleanup(stub.server.Close)
+	return stub
+}
+
+func newTestWhisper(t *testing.T, stub *whisperStub, extra map[string]string) *WhisperTranscriber {
+	t.Helper()
+	cfg := map[string]string{
+		"url":         stub.server.URL,
+		"sample_rate": "8000",
+	}
+	for key, value := range extra {
+		cfg[key] = value
+	}
+
+	transcriber, err := NewTranscriber("whisper", cfg)
+	if err != nil {
+		t.Fatalf("NewTranscriber: %v", err)
+	}
+	if err := transcriber.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	return transcriber.(*WhisperTranscriber)
+}
+
+func verboseJSON(t *testing.T, response whisperResponse) string {
+	t.Helper()
+	data, err := json.Marshal(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func logprob(v float64) *float64 { return &v }
+
+func TestWhisperUploadsWAVWithConfiguredParameters(t *testing.T) {
+	stub := newWhisperStub(t, `{"text":" Покажи логи"}`)
+	w := newTestWhisper(t, stub, map[string]string{
+		"language": "en",
+		"prompt":   "kubectl, payments",
+	})
+
+	pcm := []byte{1, 0, 2, 0, 3, 0, 4, 0}
+	text, err := w.Transcribe(context.Background(), pcm)
+	if err != nil {
+		t.Fatalf("Transcribe: %v", err)
+	}
+	if text != "Покажи логи" {
+		t.Errorf("text = %q", text)
+	}
+
+	format, size, err := readWAVHeader(bytes.NewReader(stub.wav))
+	if err != nil {
+		t.Fatalf("uploaded file is not WAV: %v", err)
+	}
+	if format.SampleRate != 8000 || format.Channels != 1 || format.BitsPerSample != 16 {
+		t.Errorf("WAV format = %+v", format)
+	}
+	if size != int64(len(pcm)) || !bytes.HasSuffix(stub.wav, pcm) {
+		t.Errorf("WAV data does not match PCM (size %d)", size)
+	}
+
+	want := map[string]string{
+		"response_format": "verbose_json",
+		"temperature":     "0.0",
+		"language":        "en",
+		"prompt":          "kubectl, payments",
+	}
+	for name, value := range want {
+		if stub.fields[name] != value {
+			t.Errorf("field %s = %q, want %q", name, stub.fields[name], value)
+		}
+	}
+	if _, ok := stub.fields["tinydiarize"]; ok {
+		t.Error("tinydiarize sent without diarize")
+	}
+}
+
+func TestWhisperDefaultsWithoutConfig(t *testing.T) {
+	stub := newWhisperStub(t, `{"text":"ok"}`)
+	w := newTestWhisper(t, stub, nil)
+
+	if _, err := w.Transcribe(context.Background(), []byte{0, 0}); err != nil {
+		t.Fatalf("Transcribe: %v", err)
+	}
+	if stub.fields["language"] != "ru" {
+		t.Errorf("language = %q, want ru", stub.fields["language"])
+	}
+	if _, ok := stub.fields["prompt"]; ok {
+		t.Error("empty prompt must not be sent")
+	}
+}
+
+func TestWhisperParsesVerboseSegments(t *testing.T) {
+	stub := newWhisperStub(t, "")
+	stub.response = verboseJSON(t, whisperResponse{
+		Language: "en",
+		Segments: []whisperSegment{
+			{Text: " Show the", Start: 0.5, End: 1.0, AvgLogprob: logprob(-0.1)},
+			{Text: " payments logs.", Start: 1.0, End: 2.25, AvgLogprob: logprob(-0.3)},
+		},
+	})
+	w := newTestWhisper(t, stub, nil)
+
+	turns, err := w.TranscribeTurns(context.Background(), []byte{0, 0})
+	if err != nil {
+		t.Fatalf("TranscribeTurns: %v", err)
+	}
+	if len(turns) != 1 {
+		t.Fatalf("got %d turns, want 1: %+v", len(turns), turns)
+	}
+
+	turn := turns[0]
+	if turn.Text != "Show the payments logs." {
+		t.Errorf("text = %q", turn.Text)
+	}
+	if turn.Speaker != "" {
+		t.Errorf("speaker = %q without diarize", turn.Speaker)
+	}
+	if turn.Language != "en" {
+		t.Errorf("language = %q, want language from response", turn.Language)
+	}
+	if turn.Offset.Seconds() != 0.5 || turn.Duration.Seconds() != 1.75 {
+		t.Errorf("offset %v, duration %v", turn.Offset, turn.Duration)
+	}
+	// exp(mean(-0.1, -0.3)) = exp(-0.2)
+	if turn.Confidence < 0.818 || turn.Confidence > 0.819 {
+		t.Errorf("confidence = %v", turn.Confidence)
+	}
+}
+
+func TestWhisperSplitsSpeakerTurns(t *testing.T) {
+	stub := newWhisperStub(t, "")
+	stub.response = verboseJSON(t, whisperResponse{
+		Segments: []whisperSegment{
+			{Text: " Покажи логи.", Start: 0, End: 1, SpeakerTurnNext: true},
+			{Text: " Сейчас открою. [SPEAKER_TURN]", Start: 1, End: 2},
+			{Text: " Спасибо.", Start: 2, End: 3, SpeakerTurnNext: true},
+		},
+	})
+	w := newTestWhisper(t, stub, map[string]string{"diarize": "true"})
+
+	turns, err := w.TranscribeTurns(context.Background(), []byte{0, 0})
+	if err != nil {
+		t.Fatalf("TranscribeTurns: %v", err)
+	}
+	if stub.fields["tinydiarize"] != "true" {
+		t.Errorf("tinydiarize = %q, want true", stub.fields["tinydiarize"])
+	}
+
+	want := []SpeakerTurn{
+		{Text: "Покажи логи.", Speaker: "S1"},
+		{Text: "Сейчас открою.", Speaker: "S2"},
+		{Text: "Спасибо.", Speaker: "S1"},
+	}
+	if len(turns) != len(want) {
+		t.Fatalf("got %d turns, want %d: %+v", len(turns), len(want), turns)
+	}
+	for i := range want {
+		if turns[i].Text != want[i].Text || turns[i].Speaker != want[i].Speaker {
+			t.Errorf("turn %d = %q/%q, want %q/%q", i, turns[i].Speaker, turns[i].Text, want[i].Speaker, want[i].Text)
+		}
+	}
+
+	// Говорящий сохраняется между фрагментами: следующий начнется с S2
+	stub.response = verboseJSON(t, whisperResponse{
+		Segments: []whisperSegment{{Text: " Пожалуйста.", Start: 0, End: 1}},
+	})
+	turns, err = w.TranscribeTurns(context.Background(), []byte{0, 0})
+	if err != nil {
+		t.Fatalf("TranscribeTurns: %v", err)
+	}
+	if len(turns) != 1 || turns[0].Speaker != "S2" {
+		t.Errorf("next fragment turns = %+v, want speaker S2", turns)
+	}
+}
+
+func TestWhisperReturnsErrorOnBadStatus(t *testing.T) {
+	stub := newWhisperStub(t, "model not loaded")
+	stub.status = http.StatusInternalServerError
+	w := newTestWhisper(t, stub, nil)
+
+	_, err := w.Transcribe(context.Background(), []byte{0, 0})
+	if err == nil {
+		t.Fatal("expected error on status 500")
+	}
+	if !strings.Contains(err.Error(), "500") || !strings.Contains(err.Error(), "model not loaded") {
+		t.Errorf("error = %v, want status and server message", err)
+	}
+}
+
+func TestWhisperReturnsServerError(t *testing.T) {
+	stub := newWhisperStub(t, `{"error":"failed to read WAV file"}`)
+	w := newTestWhisper(t, stub, nil)
+
+	if _, err := w.Transcribe(context.Background(), []byte{0, 0}); err == nil || !strings.Contains(err.Error(), "failed to read WAV file") {
+		t.Errorf("error = %v, want error from response body", err)
+	}
+}
@@ -11,6 +11,7 @@ type Config struct {
 	Vision VisionConfig `toml:"vision"`
 	AI     AIConfig     `toml:"ai"`
 	UI     UIConfig     `toml:"ui"`
+	Agent  AgentConfig  `toml:"agent"`
 }
 
 type AudioConfig struct {
@@ -19,6 +20,11 @@ type AudioConfig struct {
 	SampleRate        int               `toml:"sample_rate"`
 	BufferSize        int               `toml:"buffer_size"`
 	SilenceThreshold  float64           `toml:"silence_threshold"`
+	VADHangoverMs     int               `toml:"vad_hangover_ms"`
+	VADMinUtteranceMs int               `toml:"vad_min_utterance_ms"`
+	VADMaxUtteranceMs int               `toml:"vad_max_utterance_ms"`
+	Source            string            `toml:"source"`
+	SourcePath        string            `toml:"source_path"`
 	TranscriberType   string            `toml:"transcriber_type"`
 	TranscriberConfig map[string]string `toml:"transcriber_config"`
 }
@@ -29,21 +35,83 @@ type VisionConfig struct {
 	HotKey        string            `toml:"hot_key"`
 	OCREngine     string            `toml:"ocr_engine"`
 	OCRConfig     map[string]string `toml:"ocr_config"`
+
+	Source         string   `toml:"source"`
+	SourcePath     string   `toml:"source_path"`
+	SourceApp      string   `toml:"source_app"`
+	PollIntervalMs int      `toml:"poll_interval_ms"`
+	DedupDistance  int      `toml:"dedup_distance"`
+	ChangeGrid     int      `toml:"change_grid"`
+	CaptureCommand []string `toml:"capture_command"`
+
+	WindowWatcher string `toml:"window_watcher"`
+	WindowFile    string `toml:"window_file"`
+	FocusPollMs   int    `toml:"focus_poll_ms"`
 }
 
 type AIConfig struct {
-	Provider  string `toml:"provider"`
-	OllamaURL string `toml:"ollama_url"`
-	Model     string `toml:"model"`
-	PromptDir string `toml:"prompt_dir"`
+	Provider           string         `toml:"provider"`
+	Providers          []string       `toml:"providers"`
+	ProviderTimeoutMs  map[string]int `toml:"provider_timeout_ms"`
+	BreakerFailures    int            `toml:"breaker_failures"`
+	BreakerCooldownSec int            `toml:"breaker_cooldown_sec"`
+	OllamaURL          string         `toml:"ollama_url"`
+	Model              string         `toml:"model"`
+	CloudAPIKey        string         `toml:"cloud_api_key"`
+	CloudProvider      string         `toml:"cloud_provider"`
+	CloudModel         string         `toml:"cloud_model"`
+	CloudURL           string         `toml:"cloud_url"`
+	CloudConsent       bool           `toml:"cloud_consent"`
+	PromptDir          string         `toml:"prompt_dir"`
+	Language           string         `toml:"language"`
 }
 
 type UIConfig struct {
-	Enabled     bool    `toml:"enabled"`
-	Port        int     `toml:"port"`
-	Opacity     float64 `toml:"opacity"`
-	Position    string  `toml:"position"`
-	MaxMessages int     `toml:"max_messages"`
+	Enabled       bool    `toml:"enabled"`
+	ListenAddress string  `toml:"listen_address"`
+	AuthToken     string  `toml:"auth_token"`
+	Port          int     `toml:"port"`
+	Opacity       float64 `toml:"opacity"`
+	Position      string  `toml:"position"`
+	MaxMessages   int     `toml:"max_messages"`
+
+	SendQueueSize      int `toml:"send_queue_size"`
+	SlowClientMaxDrops int `toml:"slow_client_max_drops"`
+}
+
+type AgentConfig struct {
+	HistoryMaxEntries  int             `toml:"history_max_entries"`
+	HistoryMaxTokens   int             `toml:"history_max_tokens"`
+	HistoryWindowSec   int             `toml:"history_window_sec"`
+	CombineWindowMs    int             `toml:"combine_window_ms"`
+	TriggerCooldownSec int             `toml:"trigger_cooldown_sec"`
+	Triggers           []TriggerRule   `toml:"triggers"`
+	ControlSocket      string          `toml:"control_socket"`
+	Redaction          RedactionConfig `toml:"redaction"`
+}
+
+// RedactionConfig - маскирование секретов и персональных данных перед отправкой текста в AI
+type RedactionConfig struct {
+	Enabled          bool            `toml:"enabled"`
+	RuleSets         []string        `toml:"rule_sets"`
+	Rules            []RedactionRule `toml:"rules"`
+	EntropyMinLength int             `toml:"entropy_min_length"`
+	EntropyThreshold float64         `toml:"entropy_threshold"`
+}
+
+// RedactionRule - пользовательское правило маскирования (Go regexp)
+type RedactionRule struct {
+	Name    string `toml:"name"`
+	Pattern string `toml:"pattern"`
+}
+
+// TriggerRule - правило, по которому фраза из транскрипции вызывает немедленный снимок экрана
+type TriggerRule struct {
+	Name        string   `toml:"name"`
+	Keywords    []string `toml:"keywords"`
+	Pattern     string   `toml:"pattern"`
+	Priority    int      `toml:"priority"`
+	CooldownSec int      `toml:"cooldown_sec"`
 }
 
 func Load(path string) (*Config, error) {
@@ -0,0 +1,236 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Команды управляющего канала
+const (
+	CommandCapture = "capture" // сделать снимок экрана и проанализировать его
+)
+
+const (
+	clientTimeout  = 15 * time.Second
+	maxRequestSize = 64 << 10
+)
+
+// Request - команда, одна JSON строка на соединение
+type Request struct {
+	Command string `json:"command"`
+	Note    string `json:"note,omitempty"` // комментарий пользователя, например к снимку
+}
+
+// Response - ответ агента на команду
+type Response struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// Handler выполняет команду; ошибка возвращается клиенту
+type Handler func(ctx context.Context, req Request) error
+
+// Server - локальный управляющий канал агента на Unix сокете. Через него демоны горячих клавиш
+// (xbindkeys, sxhkd) и команда `cluely trigger capture` обращаются к запущенному агенту.
+// Сокет доступен только владельцу (0600) с момента появления по пути, поэтому команды
+// принимаются только от текущего пользователя.
+type Server struct {
+	path     string
+	handler  Handler
+	listener net.Listener
+	wg       sync.WaitGroup
+}
+
+func NewServer(path string, handler Handler) *Server {
+	if path == "" {
+		path = DefaultSocketPath()
+	}
+	return &Server{path: path, handler: handler}
+}
+
+// DefaultSocketPath возвращает путь к сокету: $XDG_RUNTIME_DIR/cluely.sock
+// или временный каталог с uid в имени, если XDG_RUNTIME_DIR не задан
+func DefaultSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "cluely.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("cluely-%d.sock", os.Getuid()))
+}
+
+// Path возвращает путь к сокету
+func (s *Server) Path() string {
+	return s.path
+}
+
+func (s *Server) Start(ctx context.Context) error {
+	if err := removeStaleSocket(s.path); err != nil {
+		return err
+	}
+
+	listener, err := listenPrivate(s.path)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+
+	s.wg.Add(1)
+	go s.acceptLoop(ctx)
+
+	log.Printf("🎛️  Control socket listening on %s", s.path)
+	return nil
+}
+
+// listenPrivate создает сокет во временном каталоге с правами 0700 рядом с path, выставляет
+// ему права 0600 и только потом делает жесткую ссылку на path. Иначе сокет создается с umask
+// процесса, и до chmod к нему может подключиться другой пользователь (в /tmp это реально).
+// Link, в отличие от Rename, не заменяет файл, появившийся по пути после removeStaleSocket.
+func listenPrivate(path string) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".cluely-")
+	if err != nil {
+		return nil, fmt.Errorf("control socket: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	private := filepath.Join(dir, "sock")
+	listener, err := net.Listen("unix", private)
+	if err != nil {
+		return nil, fmt.Errorf("control socket: %w", err)
+	}
+	// Временный путь удаляется вместе с каталогом, а path - в Stop
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+
+	if err := os.Chmod(private, 0o600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("control socket permissions: %w", err)
+	}
+	if err := os.Link(private, path); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("control socket: %w", err)
+	}
+	return listener, nil
+}
+
+// removeStaleSocket удаляет сокет, оставшийся от упавшего процесса, но не трогает
+// сокет работающего агента и файлы, которые сокетом не являются
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("control socket: %w", err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("control socket %s exists and is not a socket, refusing to remove it", path)
+	}
+
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("control socket %s is in use: another agent is running", path)
+	}
+	return os.Remove(path)
+}
+
+func (s *Server) acceptLoop(ctx context.Context) {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("❌ Control socket accept error: %v", err)
+			}
+			return
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serve(ctx, conn)
+		}()
+	}
+}
+
+func (s *Server) serve(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(clientTimeout))
+
+	var resp Response
+	var req Request
+	if err := json.NewDecoder(io.LimitReader(conn, maxRequestSize)).Decode(&req); err != nil {
+		resp.Error = fmt.Sprintf("invalid request: %v", err)
+	} else if err := s.handler(ctx, req); err != nil {
+		resp.Error = err.Error()
+	} else {
+		resp.OK = true
+	}
+
+	if !resp.OK {
+		log.Printf("⚠️  Control command %q failed: %s", req.Command, resp.Error)
+	}
+	json.NewEncoder(conn).Encode(resp)
+}
+
+func (s *Server) Stop() {
+	if s.listener == nil {
+		return
+	}
+	s.listener.Close()
+	s.wg.Wait()
+	os.Remove(s.path)
+}
+
+// Send отправляет команду запущенному агенту и возвращает ошибку, если агент ее не выполнил
+func Send(path string, req Request) error {
+	if path == "" {
+		path = DefaultSocketPath()
+	}
+
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return fmt.Errorf("agent is not running (control socket %s): %w", path, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(clientTimeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return err
+	}
+
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return fmt.Errorf("read agent response: %w", err)
+	}
+	if !resp.OK {
+		return errors.New(resp.Error)
+	}
+	return nil
+}
+
+// SxhkdChord переводит горячую клавишу из vision.hot_key ("Ctrl+Shift+S") в запись sxhkd
+// ("ctrl + shift + s"), чтобы подсказать пользователю готовую привязку
+func SxhkdChord(hotkey string) string {
+	parts := strings.Split(hotkey, "+")
+	for i, part := range parts {
+		part = strings.ToLower(strings.TrimSpace(part))
+		switch part {
+		case "control":
+			part = "ctrl"
+		case "win", "meta", "cmd":
+			part = "super"
+		}
+		parts[i] = part
+	}
+	return strings.Join(parts, " + ")
+}
@@ -0,0 +1,103 @@
+//go:build unix
+
+package control
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func startServer(t *testing.T, path string, handler Handler) *Server {
+	t.Helper()
+	s := NewServer(path, handler)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(s.Stop)
+	return s
+}
+
+func TestServerSocketIsPrivate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cluely.sock")
+
+	var got Request
+	startServer(t, path, func(ctx context.Context, req Request) error {
+		got = req
+		return nil
+	})
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSocket == 0 || info.Mode().Perm() != 0o600 {
+		t.Errorf("socket mode = %v, want socket with 0600", info.Mode())
+	}
+	// Временный каталог, в котором создавался сокет, не остается
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("leftover files next to the socket: %v", entries)
+	}
+
+	if err := Send(path, Request{Command: CommandCapture, Note: "ошибка"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got.Command != CommandCapture || got.Note != "ошибка" {
+		t.Errorf("handler got %+v", got)
+	}
+}
+
+func TestServerKeepsRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cluely.sock")
+	if err := os.WriteFile(path, []byte("notes"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewServer(path, func(ctx context.Context, req Request) error { return nil })
+	err := s.Start(context.Background())
+	if err == nil {
+		s.Stop()
+		t.Fatal("Start replaced a regular file")
+	}
+	if !strings.Contains(err.Error(), "not a socket") {
+		t.Errorf("error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "notes" {
+		t.Errorf("file content = %q, want it untouched", data)
+	}
+}
+
+func TestServerReplacesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cluely.sock")
+
+	// Сокет упавшего процесса: файл есть, но никто не слушает
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	startServer(t, path, func(ctx context.Context, req Request) error { return nil })
+	if err := Send(path, Request{Command: CommandCapture}); err != nil {
+		t.Errorf("Send: %v", err)
+	}
+}
+
+func TestServerRefusesLiveSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cluely.sock")
+	startServer(t, path, func(ctx context.Context, req Request) error { return nil })
+
+	second := NewServer(path, func(ctx context.Context, req Request) error { return nil })
+	err := second.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "another agent is running") {
+		t.Errorf("second Start = %v, want in use error", err)
+	}
+	if err := Send(path, Request{Command: CommandCapture}); err != nil {
+		t.Errorf("first agent unreachable: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package ui
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// defaultListenAddress - сервер UI по умолчанию доступен только с этой машины: через WebSocket
+// можно делать снимки экрана, задавать вопросы и ставить захват на паузу, а replay отдает
+// фрагменты OCR и транскрипции
+const defaultListenAddress = "127.0.0.1"
+
+// listenAddress возвращает ui.listen_address или 127.0.0.1
+func (s *Server) listenAddress() string {
+	if s.cfg.ListenAddress == "" {
+		return defaultListenAddress
+	}
+	return s.cfg.ListenAddress
+}
+
+// checkAccess проверяет, что сервер, доступный из сети, защищен токеном
+func (s *Server) checkAccess() error {
+	if isLoopbackHost(s.listenAddress()) || s.cfg.AuthToken != "" {
+		return nil
+	}
+	return fmt.Errorf("ui.listen_address %q is reachable from other hosts: set ui.auth_token", s.listenAddress())
+}
+
+// checkOrigin отклоняет WebSocket и запросы из браузера со страниц чужих сайтов: любая
+// открытая вкладка может обратиться к localhost. Запросы без Origin приходят не из браузера.
+// Если сервер слушает только loopback, допустимы только страницы с loopback адреса - так
+// DNS rebinding не проходит проверку Origin == Host.
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if isLoopbackHost(u.Hostname()) {
+		return true
+	}
+	// Страница, открытая по сетевому адресу агента
+	return !isLoopbackHost(s.listenAddress()) && strings.EqualFold(u.Host, r.Host)
+}
+
+// authorized проверяет ui.auth_token: заголовок "Authorization: Bearer <token>" или ?token=.
+// Без настроенного токена пропускает все запросы.
+func (s *Server) authorized(r *http.Request) bool {
+	if s.cfg.AuthToken == "" {
+		return true
+	}
+
+	token := r.URL.Query().Get("token")
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		token = bearer
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.AuthToken)) == 1
+}
+
+// protect пропускает к handler только запросы с ui.auth_token (если он задан) и без чужого
+// Origin: иначе любая открытая в браузере страница может отправить форму на localhost
+func (s *Server) protect(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !s.checkOrigin(r) {
+			http.Error(w, "cross-origin request rejected", http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// isLoopbackHost сообщает, что имя или адрес указывают на эту машину
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
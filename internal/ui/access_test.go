@@ -0,0 +1,173 @@
+package ui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cluely/internal/config"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name   string
+		listen string
+		host   string
+		origin string
+		want   bool
+	}{
+		{name: "no origin", host: "127.0.0.1:8080", want: true},
+		{name: "overlay page", host: "localhost:8080", origin: "http://localhost:8080", want: true},
+		{name: "loopback ip", host: "127.0.0.1:8080", origin: "http://127.0.0.1:8080", want: true},
+		{name: "ipv6 loopback", host: "[::1]:8080", origin: "http://[::1]:8080", want: true},
+		{name: "foreign site", host: "localhost:8080", origin: "https://evil.example", want: false},
+		{name: "dns rebinding", host: "evil.example:8080", origin: "http://evil.example:8080", want: false},
+		{name: "opaque origin", host: "localhost:8080", origin: "null", want: false},
+		{name: "network page", listen: "0.0.0.0", host: "192.168.1.5:8080", origin: "http://192.168.1.5:8080", want: true},
+		{name: "network foreign site", listen: "0.0.0.0", host: "192.168.1.5:8080", origin: "http://evil.example", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(config.UIConfig{ListenAddress: tt.listen})
+			r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+			r.Host = tt.host
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if got := s.checkOrigin(r); got != tt.want {
+				t.Errorf("checkOrigin = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckAccess(t *testing.T) {
+	tests := []struct {
+		cfg     config.UIConfig
+		wantErr bool
+	}{
+		{cfg: config.UIConfig{}},
+		{cfg: config.UIConfig{ListenAddress: "localhost"}},
+		{cfg: config.UIConfig{ListenAddress: "::1"}},
+		{cfg: config.UIConfig{ListenAddress: "0.0.0.0"}, wantErr: true},
+		{cfg: config.UIConfig{ListenAddress: "192.168.1.5"}, wantErr: true},
+		{cfg: config.UIConfig{ListenAddress: "0.0.0.0", AuthToken: "s3cret"}},
+	}
+
+	for _, tt := range tests {
+		err := NewServer(tt.cfg).checkAccess()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkAccess(%q, token %q) = %v", tt.cfg.ListenAddress, tt.cfg.AuthToken, err)
+		}
+	}
+}
+
+// dialWebSocket подключается к /ws тестового сервера с заданными заголовками
+func dialWebSocket(server *httptest.Server, query string, header http.Header) (*websocket.Conn, int, error) {
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws"+query, header)
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	return conn, status, err
+}
+
+func TestWebSocketHandshakeRejectsForeignOrigin(t *testing.T) {
+	s := NewServer(config.UIConfig{Enabled: true})
+	server := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	defer server.Close()
+
+	conn, status, err := dialWebSocket(server, "", http.Header{"Origin": {"https://evil.example"}})
+	if err == nil {
+		conn.Close()
+		t.Fatal("handshake from a foreign origin succeeded")
+	}
+	if status != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", status)
+	}
+	if m := s.Metrics(); m.ClientsTotal != 0 {
+		t.Errorf("foreign client registered: %+v", m)
+	}
+
+	conn, _, err = dialWebSocket(server, "", http.Header{"Origin": {server.URL}})
+	if err != nil {
+		t.Fatalf("handshake from the overlay page: %v", err)
+	}
+	conn.Close()
+}
+
+func TestWebSocketHandshakeRequiresToken(t *testing.T) {
+	s := NewServer(config.UIConfig{Enabled: true, ListenAddress: "0.0.0.0", AuthToken: "s3cret"})
+	server := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		query  string
+		header http.Header
+		want   int
+	}{
+		{name: "no token", want: http.StatusUnauthorized},
+		{name: "wrong token", query: "?token=guess", want: http.StatusUnauthorized},
+		{name: "query token", query: "?token=s3cret", want: http.StatusSwitchingProtocols},
+		{name: "bearer token", header: http.Header{"Authorization": {"Bearer s3cret"}}, want: http.StatusSwitchingProtocols},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, status, err := dialWebSocket(server, tt.query, tt.header)
+			if err == nil {
+				conn.Close()
+			}
+			if status != tt.want {
+				t.Errorf("status = %d (%v), want %d", status, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestProtectedRoute(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    config.UIConfig
+		header http.Header
+		query  string
+		want   int
+	}{
+		{name: "local client", want: http.StatusAccepted},
+		{name: "overlay page", header: http.Header{"Origin": {"http://localhost:8080"}}, want: http.StatusAccepted},
+		{name: "form from a foreign site", header: http.Header{"Origin": {"https://evil.example"}}, want: http.StatusForbidden},
+		{name: "network without token", cfg: config.UIConfig{ListenAddress: "0.0.0.0", AuthToken: "s3cret"}, want: http.StatusUnauthorized},
+		{name: "network with token", cfg: config.UIConfig{ListenAddress: "0.0.0.0", AuthToken: "s3cret"}, header: http.Header{"Authorization": {"Bearer s3cret"}}, want: http.StatusAccepted},
+		{name: "network with query token", cfg: config.UIConfig{ListenAddress: "0.0.0.0", AuthToken: "s3cret"}, query: "?token=s3cret", want: http.StatusAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := NewServer(tt.cfg).protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusAccepted)
+			}))
+
+			r := httptest.NewRequest(http.MethodPost, "/screenshot"+tt.query, strings.NewReader("png"))
+			r.Host = "localhost:8080"
+			for key, values := range tt.header {
+				r.Header[key] = values
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if called != (tt.want == http.StatusAccepted) {
+				t.Errorf("handler called = %v", called)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package ui
+
+import (
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Время на запись одного сообщения клиенту
+	writeWait = 10 * time.Second
+	// Клиент, не ответивший pong за это время, считается отвалившимся
+	pongWait = 60 * time.Second
+	// Пинги идут чаще, чем истекает pongWait
+	pingPeriod = pongWait * 9 / 10
+	// Максимальный размер входящего сообщения от страницы
+	maxMessageSize = 4096
+)
+
+// client - одно WebSocket соединение. Читает из сокета только readPump, пишет только writePump
+// (gorilla/websocket допускает по одному читателю и писателю на соединение).
+type client struct {
+	hub  *hub
+	conn *websocket.Conn
+	addr string
+	send chan []byte // закрывается хабом при отключении
+
+	onMessage func(c *client, data []byte) // обработчик сообщений от страницы
+
+	drops int // сообщений подряд, не попавших в очередь; защищено hub.mu
+
+	// Последнее промежуточное сообщение по ключу и порядок ключей; защищено hub.mu.
+	// wake будит writePump, когда появилось новое.
+	latest      map[string][]byte
+	latestOrder []string
+	wake        chan struct{}
+}
+
+func newClient(h *hub, conn *websocket.Conn, onMessage func(c *client, data []byte)) *client {
+	return &client{
+		hub:       h,
+		conn:      conn,
+		addr:      conn.RemoteAddr().String(),
+		send:      make(chan []byte, h.queueSize),
+		onMessage: onMessage,
+		latest:    make(map[string][]byte),
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// setLatest заменяет промежуточное сообщение key; true, если предыдущее так и не было
+// отправлено. Вызывается под hub.mu.
+func (c *client) setLatest(key string, data []byte) bool {
+	_, replaced := c.latest[key]
+	if !replaced {
+		c.latestOrder = append(c.latestOrder, key)
+	}
+	c.latest[key] = data
+
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+	return replaced
+}
+
+// discardLatest отбрасывает неотправленное промежуточное сообщение key; вызывается под hub.mu
+func (c *client) discardLatest(key string) {
+	if _, ok := c.latest[key]; !ok {
+		return
+	}
+	delete(c.latest, key)
+	for i, k := range c.latestOrder {
+		if k == key {
+			c.latestOrder = append(c.latestOrder[:i], c.latestOrder[i+1:]...)
+			break
+		}
+	}
+}
+
+// takeLatest забирает промежуточные сообщения в порядке появления; вызывается под hub.mu
+func (c *client) takeLatest() [][]byte {
+	messages := make([][]byte, 0, len(c.latestOrder))
+	for _, key := range c.latestOrder {
+		messages = append(messages, c.latest[key])
+	}
+	c.latest = make(map[string][]byte)
+	c.latestOrder = c.latestOrder[:0]
+	return messages
+}
+
+// readPump передает команды страницы в onMessage, обрабатывает pong и close фреймы
+// и замечает обрыв соединения
+func (c *client) readPump() {
+	defer func() {
+		c.hub.unregister(c)
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure, websocket.CloseNoStatusReceived) {
+				log.Printf("⚠️  WebSocket read error (%s): %v", c.addr, err)
+			}
+			return
+		}
+		c.onMessage(c, data)
+	}
+}
+
+// writePump отправляет сообщения из очереди и пинги; завершается, когда хаб закрывает очередь
+func (c *client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case message, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				log.Printf("⚠️  Error sending to client (%s): %v", c.addr, err)
+				return
+			}
+
+		case <-c.wake:
+			for _, message := range c.hub.takeLatest(c) {
+				c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+				if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+					log.Printf("⚠️  Error sending to client (%s): %v", c.addr, err)
+					return
+				}
+			}
+
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package ui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// Команды клиент -> сервер (поле "command" сообщения типа "command")
+const (
+	CommandPause    = "pause"    // target: "audio", "vision" или пусто - оба
+	CommandResume   = "resume"   // target: как у pause
+	CommandCapture  = "capture"  // text: необязательный комментарий к снимку
+	CommandAsk      = "ask"      // text: вопрос в свободной форме
+	CommandFeedback = "feedback" // hint_id, value: "useful" или "wrong"
+	CommandPin      = "pin"      // hint_id
+	CommandUnpin    = "unpin"    // hint_id
+)
+
+// Значения value для CommandFeedback
+const (
+	FeedbackUseful = "useful"
+	FeedbackWrong  = "wrong"
+)
+
+// Типы сообщений, связанные с командами
+const (
+	TypeCommand = "command" // клиент -> сервер
+	TypeAck     = "ack"     // ответ на команду, только отправившему клиенту
+	TypeStatus  = "status"  // состояние пауз, всем клиентам
+	TypePin     = "pin"     // подсказка закреплена или откреплена, всем клиентам
+)
+
+// Сколько команд может ждать агента; при переполнении клиент сразу получает ошибку
+const commandQueueSize = 16
+
+// CommandMessage - команда от страницы. ID задает клиент, он возвращается в AckMessage.
+type CommandMessage struct {
+	Version int    `json:"v"`
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Command string `json:"command"`
+	Target  string `json:"target,omitempty"`
+	Text    string `json:"text,omitempty"`
+	HintID  string `json:"hint_id,omitempty"`
+	Value   string `json:"value,omitempty"`
+}
+
+// Command - проверенная команда, которую агент получает из Server.Commands
+// и на которую отвечает через Server.Ack или Server.AckHint
+type Command struct {
+	CommandMessage
+	client *client
+}
+
+// AckMessage - результат команды с ID из CommandMessage
+type AckMessage struct {
+	Header
+	ID      string `json:"id"`
+	Command string `json:"command"`
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+	HintID  string `json:"hint_id,omitempty"` // подсказка с ответом на CommandAsk
+}
+
+// StatusMessage - что сейчас приостановлено; приходит после info и при каждом изменении
+type StatusMessage struct {
+	Header
+	AudioPaused  bool `json:"audio_paused"`
+	VisionPaused bool `json:"vision_paused"`
+}
+
+// PinMessage сообщает, что подсказка закреплена или откреплена
+type PinMessage struct {
+	Header
+	HintID string `json:"hint_id"`
+	Pinned bool   `json:"pinned"`
+}
+
+func (m CommandMessage) validate() error {
+	if m.Type != TypeCommand {
+		return fmt.Errorf("unsupported message type %q", m.Type)
+	}
+	if m.Version > SchemaVersion {
+		return fmt.Errorf("unsupported schema version %d", m.Version)
+	}
+	if m.ID == "" {
+		return errors.New("command id is required")
+	}
+
+	switch m.Command {
+	case CommandPause, CommandResume:
+		if m.Target != "" && m.Target != "audio" && m.Target != "vision" {
+			return fmt.Errorf("unknown target %q", m.Target)
+		}
+	case CommandCapture:
+	case CommandAsk:
+		if m.Text == "" {
+			return errors.New("question text is required")
+		}
+	case CommandFeedback:
+		if m.HintID == "" {
+			return errors.New("hint_id is required")
+		}
+		if m.Value != FeedbackUseful && m.Value != FeedbackWrong {
+			return fmt.Errorf("feedback value must be %q or %q", FeedbackUseful, FeedbackWrong)
+		}
+	case CommandPin, CommandUnpin:
+		if m.HintID == "" {
+			return errors.New("hint_id is required")
+		}
+	default:
+		return fmt.Errorf("unknown command %q", m.Command)
+	}
+	return nil
+}
+
+// handleMessage разбирает сообщение клиента и передает команду агенту.
+// Ошибки разбора и переполненная очередь подтверждаются сразу, без агента.
+func (s *Server) handleMessage(c *client, data []byte) {
+	var message CommandMessage
+	if err := json.Unmarshal(data, &message); err != nil {
+		s.reply(c, message, "", fmt.Errorf("invalid message: %w", err))
+		return
+	}
+	if err := message.validate(); err != nil {
+		s.reply(c, message, "", err)
+		return
+	}
+
+	select {
+	case s.commands <- Command{CommandMessage: message, client: c}:
+	default:
+		s.reply(c, message, "", errors.New("agent is busy, try again"))
+	}
+}
+
+// Commands возвращает канал команд от клиентов
+func (s *Server) Commands() <-chan Command {
+	return s.commands
+}
+
+// Ack отвечает клиенту на команду; err == nil означает успех
+func (s *Server) Ack(cmd Command, err error) {
+	s.reply(cmd.client, cmd.CommandMessage, "", err)
+}
+
+// AckHint отвечает на команду, результатом которой стала подсказка hintID
+func (s *Server) AckHint(cmd Command, hintID string, err error) {
+	s.reply(cmd.client, cmd.CommandMessage, hintID, err)
+}
+
+func (s *Server) reply(c *client, message CommandMessage, hintID string, err error) {
+	ack := AckMessage{
+		Header:  newHeader(TypeAck),
+		ID:      message.ID,
+		Command: message.Command,
+		OK:      err == nil,
+		HintID:  hintID,
+	}
+	if err != nil {
+		ack.Error = err.Error()
+		log.Printf("⚠️  UI command %q (%s) failed: %v", message.Command, message.ID, err)
+	}
+
+	// Клиент мог отключиться, пока команда выполнялась - тогда ответ просто теряется
+	s.hub.send(c, ack)
+}
+
+// SetStatus запоминает состояние пауз и рассылает его клиентам
+func (s *Server) SetStatus(audioPaused, visionPaused bool) {
+	s.hints.mu.Lock()
+	defer s.hints.mu.Unlock()
+
+	s.status = StatusMessage{
+		Header:       newHeader(TypeStatus),
+		AudioPaused:  audioPaused,
+		VisionPaused: visionPaused,
+	}
+	s.hub.broadcast(s.status, "")
+}
+
+// PinHint закрепляет подсказку: она не вытесняется из replay новыми подсказками
+func (s *Server) PinHint(id string, pinned bool) error {
+	s.hints.mu.Lock()
+	defer s.hints.mu.Unlock()
+
+	if err := s.hints.pin(id, pinned); err != nil {
+		return err
+	}
+	s.hub.broadcast(PinMessage{
+		Header: newHeader(TypePin),
+		HintID: id,
+		Pinned: pinned,
+	}, "")
+	return nil
+}
+
+// Hint возвращает сохраненную подсказку по ID
+func (s *Server) Hint(id string) (HintMessage, bool) {
+	s.hints.mu.Lock()
+	defer s.hints.mu.Unlock()
+
+	message := s.hints.find(id)
+	if message == nil {
+		return HintMessage{}, false
+	}
+	return *message, true
+}
@@ -0,0 +1,187 @@
+package ui
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cluely/internal/config"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestCommandValidate(t *testing.T) {
+	command := func(name string) CommandMessage {
+		return CommandMessage{Version: SchemaVersion, Type: TypeCommand, ID: "c-1", Command: name}
+	}
+	with := func(m CommandMessage, edit func(*CommandMessage)) CommandMessage {
+		edit(&m)
+		return m
+	}
+
+	tests := []struct {
+		name    string
+		message CommandMessage
+		wantErr string
+	}{
+		{name: "pause both", message: command(CommandPause)},
+		{name: "pause audio", message: with(command(CommandPause), func(m *CommandMessage) { m.Target = "audio" })},
+		{name: "resume vision", message: with(command(CommandResume), func(m *CommandMessage) { m.Target = "vision" })},
+		{name: "unknown target", message: with(command(CommandPause), func(m *CommandMessage) { m.Target = "screen" }), wantErr: `unknown target "screen"`},
+		{name: "capture", message: command(CommandCapture)},
+		{name: "capture with note", message: with(command(CommandCapture), func(m *CommandMessage) { m.Text = "после деплоя" })},
+		{name: "ask", message: with(command(CommandAsk), func(m *CommandMessage) { m.Text = "что с payments?" })},
+		{name: "ask without text", message: command(CommandAsk), wantErr: "question text is required"},
+		{name: "feedback useful", message: with(command(CommandFeedback), func(m *CommandMessage) { m.HintID, m.Value = "7", FeedbackUseful })},
+		{name: "feedback wrong", message: with(command(CommandFeedback), func(m *CommandMessage) { m.HintID, m.Value = "7", FeedbackWrong })},
+		{name: "feedback without hint", message: with(command(CommandFeedback), func(m *CommandMessage) { m.Value = FeedbackUseful }), wantErr: "hint_id is required"},
+		{name: "feedback bad value", message: with(command(CommandFeedback), func(m *CommandMessage) { m.HintID, m.Value = "7", "meh" }), wantErr: "feedback value must be"},
+		{name: "pin", message: with(command(CommandPin), func(m *CommandMessage) { m.HintID = "7" })},
+		{name: "pin without hint", message: command(CommandPin), wantErr: "hint_id is required"},
+		{name: "unpin without hint", message: command(CommandUnpin), wantErr: "hint_id is required"},
+		{name: "unknown command", message: command("reboot"), wantErr: `unknown command "reboot"`},
+		{name: "missing id", message: with(command(CommandCapture), func(m *CommandMessage) { m.ID = "" }), wantErr: "command id is required"},
+		{name: "wrong type", message: with(command(CommandCapture), func(m *CommandMessage) { m.Type = TypeHint }), wantErr: "unsupported message type"},
+		{name: "newer version", message: with(command(CommandCapture), func(m *CommandMessage) { m.Version = SchemaVersion + 1 }), wantErr: "unsupported schema version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.message.validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validate = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validate = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// readAck берет из очереди клиента ответ на команду
+func readAck(t *testing.T, c *client) AckMessage {
+	t.Helper()
+	select {
+	case data := <-c.send:
+		var ack AckMessage
+		if err := json.Unmarshal(data, &ack); err != nil || ack.Type != TypeAck {
+			t.Fatalf("want ack, got %s (%v)", data, err)
+		}
+		return ack
+	default:
+		t.Fatal("no ack queued")
+		return AckMessage{}
+	}
+}
+
+func TestHandleMessageRejectsMalformed(t *testing.T) {
+	s := NewServer(config.UIConfig{Enabled: true})
+	c := testClient(s.hub)
+
+	s.handleMessage(c, []byte(`{"type":"command","id":"c-1"`))
+	if ack := readAck(t, c); ack.OK || !strings.HasPrefix(ack.Error, "invalid message") {
+		t.Errorf("ack = %+v", ack)
+	}
+
+	// Ошибка проверки возвращает ID и команду клиента
+	s.handleMessage(c, []byte(`{"v":1,"type":"command","id":"c-2","command":"ask"}`))
+	if ack := readAck(t, c); ack.OK || ack.ID != "c-2" || ack.Command != CommandAsk || ack.Error != "question text is required" {
+		t.Errorf("ack = %+v", ack)
+	}
+	if len(s.Commands()) != 0 {
+		t.Error("invalid command queued")
+	}
+}
+
+func TestHandleMessageQueueFull(t *testing.T) {
+	s := NewServer(config.UIConfig{Enabled: true})
+	c := testClient(s.hub)
+
+	for i := 0; i < commandQueueSize; i++ {
+		s.handleMessage(c, []byte(`{"v":1,"type":"command","id":"c-1","command":"capture"}`))
+	}
+	if len(c.send) != 0 {
+		t.Fatalf("commands within the queue size were answered: %s", <-c.send)
+	}
+
+	// Агент не разбирает очередь: следующая команда сразу получает ошибку
+	s.handleMessage(c, []byte(`{"v":1,"type":"command","id":"c-17","command":"pause"}`))
+	if ack := readAck(t, c); ack.OK || ack.ID != "c-17" || ack.Command != CommandPause || ack.Error != "agent is busy, try again" {
+		t.Errorf("ack = %+v", ack)
+	}
+	if len(s.Commands()) != commandQueueSize {
+		t.Errorf("queue has %d commands, want %d", len(s.Commands()), commandQueueSize)
+	}
+
+	// Когда агент взял команду, место освобождается
+	<-s.Commands()
+	s.handleMessage(c, []byte(`{"v":1,"type":"command","id":"c-18","command":"pause"}`))
+	if len(c.send) != 0 {
+		t.Errorf("command after the queue drained was rejected: %s", <-c.send)
+	}
+}
+
+func TestAckReachesOnlySender(t *testing.T) {
+	s := NewServer(config.UIConfig{Enabled: true})
+	server := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	defer server.Close()
+
+	connect := func() *websocket.Conn {
+		conn, _, err := dialWebSocket(server, "", nil)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		// info и status
+		for i := 0; i < 2; i++ {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return conn
+	}
+	sender, other := connect(), connect()
+	defer sender.Close()
+	defer other.Close()
+
+	sender.WriteMessage(websocket.TextMessage, []byte(`{"v":1,"type":"command","id":"c-1","command":"ask","text":"что с payments?"}`))
+	var cmd Command
+	select {
+	case cmd = <-s.Commands():
+	case <-time.After(5 * time.Second):
+		t.Fatal("command did not reach the agent")
+	}
+	s.AckHint(cmd, "8", nil)
+	s.Ack(Command{CommandMessage: CommandMessage{ID: "c-2", Command: CommandPin}, client: cmd.client}, errors.New("hint 2 is not available"))
+
+	sender.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for _, want := range []AckMessage{
+		{ID: "c-1", Command: CommandAsk, OK: true, HintID: "8"},
+		{ID: "c-2", Command: CommandPin, Error: "hint 2 is not available"},
+	} {
+		var ack AckMessage
+		if err := sender.ReadJSON(&ack); err != nil {
+			t.Fatalf("sender: %v", err)
+		}
+		if ack.Type != TypeAck || ack.ID != want.ID || ack.Command != want.Command || ack.OK != want.OK || ack.HintID != want.HintID || ack.Error != want.Error {
+			t.Errorf("ack = %+v, want %+v", ack, want)
+		}
+	}
+
+	// Общие изменения приходят всем, ответы на команды - нет
+	s.SetStatus(true, false)
+	other.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, data, err := other.ReadMessage()
+	if err != nil {
+		t.Fatalf("other client: %v", err)
+	}
+	if kind, _ := messageType(t, data); kind != TypeStatus {
+		t.Errorf("other client got %s before status: %s", kind, data)
+	}
+}
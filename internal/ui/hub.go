@@ -0,0 +1,216 @@
+package ui
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	defaultSendQueueSize = 64
+	defaultSlowClientMax = 32
+)
+
+// Metrics - счетчики WebSocket клиентов, отдаются на /metrics
+type Metrics struct {
+	Clients            int   `json:"clients"`
+	ClientsTotal       int64 `json:"clients_total"`
+	MessagesSent       int64 `json:"messages_sent"`
+	MessagesDropped    int64 `json:"messages_dropped"`
+	MessagesCoalesced  int64 `json:"messages_coalesced"`
+	SlowClientsDropped int64 `json:"slow_clients_dropped"`
+}
+
+// hub раздает сообщения подключенным клиентам. У каждого клиента своя ограниченная очередь,
+// а запись в сокет делает его writePump, поэтому broadcast никогда не ждет медленный браузер.
+// Если очередь клиента полна, сообщение для него отбрасывается; клиент, пропустивший подряд
+// больше slowClientMax сообщений, отключается - переподключившись, он начнет с чистой очереди.
+//
+// Сообщения, которые устаревают с приходом следующего (живой субтитр, накопленный текст
+// подсказки), в очередь не попадают: у клиента хранится только последнее по ключу, и writePump
+// отправляет его, когда дойдет. Поэтому медленный клиент получает меньше промежуточных
+// сообщений, а не очередь, забитую устаревшими.
+type hub struct {
+	queueSize     int
+	slowClientMax int
+
+	mu      sync.Mutex
+	clients map[*client]struct{}
+
+	clientsTotal       atomic.Int64
+	messagesSent       atomic.Int64
+	messagesDropped    atomic.Int64
+	messagesCoalesced  atomic.Int64
+	slowClientsDropped atomic.Int64
+}
+
+func newHub(queueSize, slowClientMax int) *hub {
+	if queueSize <= 0 {
+		queueSize = defaultSendQueueSize
+	}
+	if slowClientMax <= 0 {
+		slowClientMax = defaultSlowClientMax
+	}
+	return &hub{
+		queueSize:     queueSize,
+		slowClientMax: slowClientMax,
+		clients:       make(map[*client]struct{}),
+	}
+}
+
+func (h *hub) register(c *client) {
+	h.mu.Lock()
+	h.clients[c] = struct{}{}
+	count := len(h.clients)
+	h.mu.Unlock()
+
+	h.clientsTotal.Add(1)
+	log.Printf("✅ New WebSocket client connected (%s, %d connected)", c.addr, count)
+}
+
+// unregister удаляет клиента и закрывает его очередь; writePump после этого закрывает соединение.
+// Повторный вызов ничего не делает.
+func (h *hub) unregister(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[c]; !ok {
+		return
+	}
+	delete(h.clients, c)
+	close(c.send)
+	log.Printf("👋 WebSocket client disconnected (%s, %d connected)", c.addr, len(h.clients))
+}
+
+// broadcast ставит сообщение в очереди всех клиентов без блокировки. supersedes - ключ
+// промежуточного сообщения, которое это сообщение завершает (см. broadcastLatest): еще
+// не отправленное промежуточное сообщение отбрасывается, чтобы не прийти после итогового.
+func (h *hub) broadcast(message interface{}, supersedes string) {
+	h.enqueue(message, supersedes, false)
+}
+
+// broadcastReplayable рассылает сообщение, которое клиент может получить повторно через replay.
+// Клиент, в очередь которого оно не поместилось, отключается сразу: переподключившись
+// с ?since=, он получит его из replay, а не потеряет.
+func (h *hub) broadcastReplayable(message interface{}, supersedes string) {
+	h.enqueue(message, supersedes, true)
+}
+
+func (h *hub) enqueue(message interface{}, supersedes string, replayable bool) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("❌ UI message encode error: %v", err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for c := range h.clients {
+		if supersedes != "" {
+			c.discardLatest(supersedes)
+		}
+
+		select {
+		case c.send <- data:
+			c.drops = 0
+			h.messagesSent.Add(1)
+			continue
+		default:
+		}
+
+		c.drops++
+		h.messagesDropped.Add(1)
+		if replayable || c.drops > h.slowClientMax {
+			h.dropSlow(c)
+		}
+	}
+}
+
+// broadcastLatest передает клиентам промежуточное сообщение, которое заменяет предыдущее
+// с тем же ключом. Оно не занимает место в очереди и никогда не вытесняет другие сообщения.
+func (h *hub) broadcastLatest(key string, message interface{}) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("❌ UI message encode error: %v", err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for c := range h.clients {
+		if c.setLatest(key, data) {
+			h.messagesCoalesced.Add(1)
+		}
+	}
+}
+
+// takeLatest забирает промежуточные сообщения клиента в порядке их появления
+func (h *hub) takeLatest(c *client) [][]byte {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	messages := c.takeLatest()
+	h.messagesSent.Add(int64(len(messages)))
+	return messages
+}
+
+// dropSlow отключает клиента, который не успевает читать; вызывается под mu
+func (h *hub) dropSlow(c *client) {
+	delete(h.clients, c)
+	close(c.send)
+	h.slowClientsDropped.Add(1)
+	log.Printf("⚠️  Slow WebSocket client dropped (%s): %d messages behind", c.addr, c.drops)
+}
+
+// send ставит сообщение в очередь одного клиента; false, если очередь полна или клиент отключен
+func (h *hub) send(c *client, message interface{}) bool {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("❌ UI message encode error: %v", err)
+		return false
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[c]; !ok {
+		return false
+	}
+	select {
+	case c.send <- data:
+		h.messagesSent.Add(1)
+		return true
+	default:
+		h.messagesDropped.Add(1)
+		return false
+	}
+}
+
+func (h *hub) metrics() Metrics {
+	h.mu.Lock()
+	clients := len(h.clients)
+	h.mu.Unlock()
+
+	return Metrics{
+		Clients:            clients,
+		ClientsTotal:       h.clientsTotal.Load(),
+		MessagesSent:       h.messagesSent.Load(),
+		MessagesDropped:    h.messagesDropped.Load(),
+		MessagesCoalesced:  h.messagesCoalesced.Load(),
+		SlowClientsDropped: h.slowClientsDropped.Load(),
+	}
+}
+
+// closeAll отключает всех клиентов
+func (h *hub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for c := range h.clients {
+		delete(h.clients, c)
+		close(c.send)
+	}
+}
@@ -0,0 +1,177 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cluely/internal/ai"
+	"cluely/internal/config"
+
+	"github.com/gorilla/websocket"
+)
+
+// testClient - клиент без соединения: очередь читает сам тест
+func testClient(h *hub) *client {
+	c := &client{
+		hub:    h,
+		addr:   "test",
+		send:   make(chan []byte, h.queueSize),
+		latest: make(map[string][]byte),
+		wake:   make(chan struct{}, 1),
+	}
+	h.mu.Lock()
+	h.clients[c] = struct{}{}
+	h.mu.Unlock()
+	return c
+}
+
+func messageType(t *testing.T, data []byte) (string, string) {
+	t.Helper()
+	var message struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+		Hint string `json:"hint"`
+	}
+	if err := json.Unmarshal(data, &message); err != nil {
+		t.Fatalf("decode %s: %v", data, err)
+	}
+	return message.Type, message.Hint
+}
+
+func TestHubCoalescesPartials(t *testing.T) {
+	h := newHub(4, 2)
+	c := testClient(h)
+
+	for i := 1; i <= 100; i++ {
+		h.broadcastLatest(partialKey("7"), PartialHintMessage{Header: newHeader(TypePartialHint), ID: "7", Hint: strings.Repeat("x", i)})
+	}
+	h.broadcastLatest(captionKey, CaptionMessage{Header: newHeader(TypeCaption), Text: "покажи"})
+
+	if len(c.send) != 0 {
+		t.Errorf("partials took %d queue slots", len(c.send))
+	}
+	messages := h.takeLatest(c)
+	if len(messages) != 2 {
+		t.Fatalf("got %d pending messages, want latest partial and caption", len(messages))
+	}
+	if kind, hint := messageType(t, messages[0]); kind != TypePartialHint || len(hint) != 100 {
+		t.Errorf("first pending = %s with %d chars, want the latest partial", kind, len(hint))
+	}
+	if kind, _ := messageType(t, messages[1]); kind != TypeCaption {
+		t.Errorf("second pending = %s, want caption", kind)
+	}
+	if m := h.metrics(); m.MessagesCoalesced != 99 || m.MessagesDropped != 0 || m.SlowClientsDropped != 0 {
+		t.Errorf("metrics = %+v", m)
+	}
+}
+
+func TestHubFinalMessageSupersedesPartial(t *testing.T) {
+	h := newHub(4, 2)
+	c := testClient(h)
+
+	h.broadcastLatest(partialKey("7"), PartialHintMessage{Header: newHeader(TypePartialHint), ID: "7", Hint: "Pods"})
+	h.broadcastLatest(partialKey("8"), PartialHintMessage{Header: newHeader(TypePartialHint), ID: "8", Hint: "CPU"})
+	h.broadcastReplayable(&HintMessage{Header: newHeader(TypeHint), ID: "7", Hint: "Pods restart"}, partialKey("7"))
+
+	messages := h.takeLatest(c)
+	if len(messages) != 1 {
+		t.Fatalf("got %d pending messages, want only the partial of hint 8", len(messages))
+	}
+	if _, hint := messageType(t, messages[0]); hint != "CPU" {
+		t.Errorf("pending partial = %q, want CPU", hint)
+	}
+	if kind, _ := messageType(t, <-c.send); kind != TypeHint {
+		t.Errorf("queued %s, want hint", kind)
+	}
+}
+
+func TestHubDisconnectsClientThatMissesHint(t *testing.T) {
+	h := newHub(2, 10)
+	slow := testClient(h)
+	fast := testClient(h)
+
+	for i := 0; i < 2; i++ {
+		h.broadcast(TranscriptMessage{Header: newHeader(TypeTranscript), Text: "фраза"}, captionKey)
+		<-fast.send
+	}
+	// Промежуточные сообщения не переполняют очередь и не отключают клиента
+	h.broadcastLatest(partialKey("1"), PartialHintMessage{Header: newHeader(TypePartialHint), ID: "1"})
+	if m := h.metrics(); m.Clients != 2 {
+		t.Fatalf("client dropped for a partial: %+v", m)
+	}
+
+	h.broadcastReplayable(&HintMessage{Header: newHeader(TypeHint), ID: "1", Seq: 1}, partialKey("1"))
+
+	if m := h.metrics(); m.Clients != 1 || m.SlowClientsDropped != 1 {
+		t.Errorf("metrics = %+v, want the slow client disconnected", m)
+	}
+	if kind, _ := messageType(t, <-fast.send); kind != TypeHint {
+		t.Errorf("fast client got %s, want hint", kind)
+	}
+	// Очередь отключенного клиента закрыта после двух старых сообщений
+	<-slow.send
+	<-slow.send
+	if _, ok := <-slow.send; ok {
+		t.Error("slow client queue is still open")
+	}
+}
+
+func TestStreamingHintOverWebSocket(t *testing.T) {
+	s := NewServer(config.UIConfig{Enabled: true, SendQueueSize: 8})
+	server := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	// info и status
+	for i := 0; i < 2; i++ {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var text strings.Builder
+	for i := 0; i < 500; i++ {
+		text.WriteString(fmt.Sprintf("token%d ", i))
+		s.SendPartialHint("1", "audio", text.String())
+	}
+	s.SendHint("1", ai.AnalysisInput{Type: "audio"}, ai.AnalysisOutput{Hint: "done"})
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	partials := 0
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read: %v (after %d partials)", err, partials)
+		}
+		kind, hint := messageType(t, data)
+		if kind == TypeHint {
+			if hint != "done" {
+				t.Errorf("hint = %q", hint)
+			}
+			break
+		}
+		if kind != TypePartialHint {
+			t.Fatalf("unexpected %s", kind)
+		}
+		partials++
+	}
+
+	if partials > 500 {
+		t.Errorf("got %d partials for 500 chunks", partials)
+	}
+	// После итоговой подсказки промежуточные варианты не приходят
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, data, err := conn.ReadMessage(); err == nil {
+		t.Errorf("message after hint: %s", data)
+	}
+}
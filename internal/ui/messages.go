@@ -0,0 +1,131 @@
+package ui
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"cluely/internal/ai"
+)
+
+// SchemaVersion - версия формата сообщений WebSocket (поле "v"). Повышается при несовместимых
+// изменениях; новые необязательные поля версию не меняют. Описание: docs/websocket-protocol.md
+const SchemaVersion = 1
+
+// Типы сообщений сервер -> клиент
+const (
+	TypeInfo        = "info"       // приветствие при подключении
+	TypeCaption     = "caption"    // живой субтитр незавершенной фразы
+	TypeTranscript  = "transcript" // завершенная фраза
+	TypePartialHint = "partial"    // подсказка, пока модель ее генерирует
+	TypeHint        = "hint"       // итоговая подсказка с полным результатом анализа
+	TypeHintError   = "error"      // анализ не удался, потоковая подсказка не будет завершена
+)
+
+// Сколько символов исходного текста попадает в InputExcerpt
+const excerptLength = 200
+
+// Header - общие поля всех сообщений
+type Header struct {
+	Version int       `json:"v"`
+	Type    string    `json:"type"`
+	Time    time.Time `json:"time"`
+}
+
+func newHeader(messageType string) Header {
+	return Header{Version: SchemaVersion, Type: messageType, Time: time.Now()}
+}
+
+// InfoMessage отправляется каждому клиенту сразу после подключения, до replay
+type InfoMessage struct {
+	Header
+	Message     string `json:"message"`
+	Seq         uint64 `json:"seq"`          // номер последней подсказки на сервере
+	Epoch       string `json:"epoch"`        // идентификатор запуска агента, к которому относится seq
+	MaxMessages int    `json:"max_messages"` // сколько подсказок показывать (ui.max_messages)
+}
+
+// CaptionMessage - промежуточный результат распознавания речи; пустой Text стирает субтитр
+type CaptionMessage struct {
+	Header
+	Text string `json:"text"`
+}
+
+// TranscriptMessage - завершенная фраза, заменяет живой субтитр
+type TranscriptMessage struct {
+	Header
+	Speaker string `json:"speaker,omitempty"`
+	Text    string `json:"text"`
+}
+
+// PartialHintMessage - накопленный на данный момент текст подсказки ID
+type PartialHintMessage struct {
+	Header
+	ID     string `json:"id"`
+	Source string `json:"source"` // "audio", "vision", "combined" или "question"
+	Hint   string `json:"hint"`
+}
+
+// HintMessage - итоговая подсказка. Завершает поток PartialHintMessage с тем же ID,
+// сохраняется для replay и получает порядковый номер Seq.
+type HintMessage struct {
+	Header
+	ID         string       `json:"id"`
+	Seq        uint64       `json:"seq"`
+	Source     string       `json:"source"`
+	Hint       string       `json:"hint"`
+	Tasks      []string     `json:"tasks"`
+	Warnings   []string     `json:"warnings"`
+	Confidence float64      `json:"confidence"` // 0.0 - 1.0
+	Provider   string       `json:"provider"`
+	Input      InputExcerpt `json:"input"`
+	Pinned     bool         `json:"pinned"`
+}
+
+// HintErrorMessage завершает поток PartialHintMessage с тем же ID, если анализ не удался.
+// В replay не сохраняется.
+type HintErrorMessage struct {
+	Header
+	ID     string `json:"id"`
+	Source string `json:"source"`
+	Error  string `json:"error"`
+}
+
+// InputExcerpt - начало текста, по которому построена подсказка (уже после маскирования секретов)
+type InputExcerpt struct {
+	Transcript string `json:"transcript,omitempty"`
+	Speaker    string `json:"speaker,omitempty"`
+	Screen     string `json:"screen,omitempty"`
+	App        string `json:"app,omitempty"`
+	Note       string `json:"note,omitempty"`
+	Question   string `json:"question,omitempty"`
+}
+
+func newInputExcerpt(input ai.AnalysisInput) InputExcerpt {
+	return InputExcerpt{
+		Transcript: excerpt(input.TranscriptText),
+		Speaker:    input.Speaker,
+		Screen:     excerpt(input.OCRText),
+		App:        input.App,
+		Note:       excerpt(input.Note),
+		Question:   excerpt(input.Question),
+	}
+}
+
+// excerpt обрезает текст до excerptLength символов по границе руны
+func excerpt(text string) string {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) <= excerptLength {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:excerptLength])) + "…"
+}
+
+// nonNil нужен, чтобы пустые списки уходили как [], а не null
+func nonNil(items []string) []string {
+	if items == nil {
+		return []string{}
+	}
+	return items
+}
@@ -0,0 +1,218 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"cluely/internal/ai"
+	"cluely/internal/config"
+)
+
+// documentedMessages возвращает примеры сообщений из docs/websocket-protocol.md:
+// однострочные объекты и многострочные блоки ```json целиком
+func documentedMessages(t *testing.T) []string {
+	t.Helper()
+	data, err := os.ReadFile("../../docs/websocket-protocol.md")
+	if err != nil {
+		t.Fatalf("read protocol docs: %v", err)
+	}
+
+	var examples []string
+	blocks := strings.Split(string(data), "```json\n")
+	for _, block := range blocks[1:] {
+		block = block[:strings.Index(block, "```")]
+		if strings.HasPrefix(block, "{\n") {
+			examples = append(examples, block)
+			continue
+		}
+		for _, line := range strings.Split(strings.TrimSpace(block), "\n") {
+			examples = append(examples, line)
+		}
+	}
+	return examples
+}
+
+// messageOf возвращает пустое сообщение Go типа, соответствующего полю type
+func messageOf(messageType string) interface{} {
+	switch messageType {
+	case TypeInfo:
+		return &InfoMessage{}
+	case TypeCaption:
+		return &CaptionMessage{}
+	case TypeTranscript:
+		return &TranscriptMessage{}
+	case TypePartialHint:
+		return &PartialHintMessage{}
+	case TypeHint:
+		return &HintMessage{}
+	case TypeHintError:
+		return &HintErrorMessage{}
+	case TypeStatus:
+		return &StatusMessage{}
+	case TypePin:
+		return &PinMessage{}
+	case TypeAck:
+		return &AckMessage{}
+	case TypeCommand:
+		return &CommandMessage{}
+	}
+	return nil
+}
+
+func TestDocumentedMessagesRoundTrip(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, example := range documentedMessages(t) {
+		// В документации время часто опущено
+		example = strings.ReplaceAll(example, `"time":"..."`, `"time":"2026-10-18T10:15:00+03:00"`)
+
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(example), &fields); err != nil {
+			t.Errorf("documented example is not JSON: %v\n%s", err, example)
+			continue
+		}
+		messageType, _ := fields["type"].(string)
+		seen[messageType] = true
+		if fields["v"] != float64(SchemaVersion) {
+			t.Errorf("%s: v = %v, want %d", messageType, fields["v"], SchemaVersion)
+		}
+
+		message := messageOf(messageType)
+		if message == nil {
+			t.Errorf("documented type %q has no Go type", messageType)
+			continue
+		}
+		decoder := json.NewDecoder(strings.NewReader(example))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(message); err != nil {
+			t.Errorf("%s: %v", messageType, err)
+			continue
+		}
+
+		// Поля, которые вернет сервер, совпадают с документированными
+		encoded, err := json.Marshal(message)
+		if err != nil {
+			t.Fatalf("%s: %v", messageType, err)
+		}
+		var roundTrip map[string]interface{}
+		json.Unmarshal(encoded, &roundTrip)
+		if !reflect.DeepEqual(roundTrip, fields) {
+			t.Errorf("%s round trip:\n got %s\nwant %s", messageType, encoded, example)
+		}
+	}
+
+	for _, messageType := range []string{TypeInfo, TypeCaption, TypeTranscript, TypePartialHint, TypeHint, TypeHintError, TypeStatus, TypePin, TypeAck, TypeCommand} {
+		if !seen[messageType] {
+			t.Errorf("message type %q is not documented", messageType)
+		}
+	}
+}
+
+// decodeFields разбирает сообщение из очереди клиента в карту полей
+func decodeFields(t *testing.T, data []byte) map[string]interface{} {
+	t.Helper()
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("decode %s: %v", data, err)
+	}
+	if fields["v"] != float64(SchemaVersion) {
+		t.Errorf("v = %v in %s", fields["v"], data)
+	}
+	return fields
+}
+
+func TestServerMessageShapes(t *testing.T) {
+	s := NewServer(config.UIConfig{Enabled: true})
+	c := testClient(s.hub)
+
+	s.SendTranscript("", "покажи логи")
+	if fields := decodeFields(t, <-c.send); fields["type"] != TypeTranscript || fields["text"] != "покажи логи" {
+		t.Errorf("transcript = %v", fields)
+	} else if _, ok := fields["speaker"]; ok {
+		t.Error("empty speaker is sent")
+	}
+
+	long := strings.Repeat("ж", excerptLength+50)
+	s.SendHint("7", ai.AnalysisInput{Type: "vision", OCRText: "  " + long, App: "Terminal"}, ai.AnalysisOutput{Hint: "ok", Provider: "mock"})
+	data := <-c.send
+	fields := decodeFields(t, data)
+
+	// Пустые списки уходят как [], а не null
+	if !bytes.Contains(data, []byte(`"tasks":[]`)) || !bytes.Contains(data, []byte(`"warnings":[]`)) {
+		t.Errorf("hint lists are not empty arrays: %s", data)
+	}
+	if fields["seq"] != float64(1) || fields["source"] != "vision" || fields["pinned"] != false {
+		t.Errorf("hint = %s", data)
+	}
+
+	input, _ := fields["input"].(map[string]interface{})
+	if len(input) != 2 || input["app"] != "Terminal" {
+		t.Errorf("input = %v, want only screen and app", input)
+	}
+	screen, _ := input["screen"].(string)
+	if utf8.RuneCountInString(screen) != excerptLength+1 || !strings.HasPrefix(screen, "жж") || !strings.HasSuffix(screen, "…") {
+		t.Errorf("screen excerpt has %d runes: %q", utf8.RuneCountInString(screen), screen)
+	}
+
+	// Итоговая подсказка декодируется обратно без потерь
+	var hint HintMessage
+	if err := json.Unmarshal(data, &hint); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := s.Hint("7")
+	if !reflect.DeepEqual(hint.Input, stored.Input) || hint.Seq != stored.Seq || !hint.Time.Equal(stored.Time) {
+		t.Errorf("decoded hint %+v differs from stored %+v", hint, stored)
+	}
+}
+
+func TestCommandSchemaVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		wantErr string
+	}{
+		{name: "current version", message: `{"v":1,"type":"command","id":"c-1","command":"capture"}`},
+		{name: "version omitted", message: `{"type":"command","id":"c-1","command":"capture"}`},
+		{name: "newer version", message: `{"v":2,"type":"command","id":"c-1","command":"capture"}`, wantErr: "unsupported schema version 2"},
+		{name: "server message type", message: `{"v":1,"type":"ack","id":"c-1","command":"capture"}`, wantErr: `unsupported message type "ack"`},
+		{name: "version is not a number", message: `{"v":"1","type":"command","id":"c-1","command":"capture"}`, wantErr: "invalid message"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(config.UIConfig{Enabled: true})
+			c := testClient(s.hub)
+			s.handleMessage(c, []byte(tt.message))
+
+			if tt.wantErr == "" {
+				select {
+				case cmd := <-s.Commands():
+					if cmd.ID != "c-1" || cmd.Command != CommandCapture {
+						t.Errorf("command = %+v", cmd.CommandMessage)
+					}
+				default:
+					t.Fatal("command was not queued")
+				}
+				if len(c.send) != 0 {
+					t.Errorf("accepted command answered before the agent: %s", <-c.send)
+				}
+				return
+			}
+
+			if len(s.Commands()) != 0 {
+				t.Error("rejected command was queued")
+			}
+			var ack AckMessage
+			if err := json.Unmarshal(<-c.send, &ack); err != nil {
+				t.Fatal(err)
+			}
+			if ack.Type != TypeAck || ack.OK || !strings.Contains(ack.Error, tt.wantErr) {
+				t.Errorf("ack = %+v, want error %q", ack, tt.wantErr)
+			}
+		})
+	}
+}
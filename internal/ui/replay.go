@@ -0,0 +1,123 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const defaultMaxMessages = 10
+
+// hintRing хранит последние подсказки, чтобы переподключившийся клиент увидел то, что пропустил.
+// Номера растут монотонно с запуска агента и начинаются заново после перезапуска, поэтому
+// к ним прилагается epoch - идентификатор запуска. Клиент передает последний увиденный номер
+// и epoch в ?since=&epoch=; номер из другого запуска ничего не значит.
+// Мьютекс держится и на время рассылки, и на время подключения клиента, поэтому подсказка
+// не теряется и не дублируется между replay и живым потоком.
+// Закрепленные подсказки не вытесняются; их может быть не больше max.
+// Подсказки содержат фрагменты OCR и транскрипции, поэтому replay получают только клиенты,
+// прошедшие проверки доступа (access.go) в handleWebSocket.
+type hintRing struct {
+	mu     sync.Mutex
+	events []*HintMessage
+	max    int
+	seq    uint64
+	epoch  string
+}
+
+func newHintRing(max int) *hintRing {
+	if max <= 0 {
+		max = defaultMaxMessages
+	}
+	return &hintRing{max: max, epoch: strconv.FormatInt(time.Now().UnixNano(), 36)}
+}
+
+// add присваивает подсказке номер и сохраняет ее; вызывается под mu
+func (r *hintRing) add(message *HintMessage) {
+	r.seq++
+	message.Seq = r.seq
+
+	r.events = append(r.events, message)
+	r.evict()
+}
+
+// evict удаляет самые старые незакрепленные подсказки сверх max
+func (r *hintRing) evict() {
+	unpinned := 0
+	for _, message := range r.events {
+		if !message.Pinned {
+			unpinned++
+		}
+	}
+
+	excess := unpinned - r.max
+	if excess <= 0 {
+		return
+	}
+	kept := r.events[:0]
+	for _, message := range r.events {
+		if !message.Pinned && excess > 0 {
+			excess--
+			continue
+		}
+		kept = append(kept, message)
+	}
+	r.events = kept
+}
+
+// pin закрепляет или открепляет подсказку; вызывается под mu
+func (r *hintRing) pin(id string, pinned bool) error {
+	message := r.find(id)
+	if message == nil {
+		return fmt.Errorf("hint %s is not available", id)
+	}
+	if message.Pinned == pinned {
+		return nil
+	}
+
+	if pinned {
+		count := 0
+		for _, event := range r.events {
+			if event.Pinned {
+				count++
+			}
+		}
+		if count >= r.max {
+			return fmt.Errorf("too many pinned hints (max %d)", r.max)
+		}
+	}
+
+	message.Pinned = pinned
+	if !pinned {
+		r.evict()
+	}
+	return nil
+}
+
+// find ищет подсказку по ID; вызывается под mu
+func (r *hintRing) find(id string) *HintMessage {
+	for _, message := range r.events {
+		if message.ID == id {
+			return message
+		}
+	}
+	return nil
+}
+
+// since возвращает сохраненные подсказки с номером больше since; вызывается под mu.
+// Если epoch клиента не совпадает с текущим, номер относится к прошлому запуску агента -
+// тогда отдается все. Клиенты без epoch узнают о перезапуске только по номеру из будущего.
+func (r *hintRing) since(since uint64, epoch string) []*HintMessage {
+	if (epoch != "" && epoch != r.epoch) || since > r.seq {
+		since = 0
+	}
+
+	var messages []*HintMessage
+	for _, message := range r.events {
+		if message.Seq > since {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
@@ -0,0 +1,148 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"cluely/internal/ai"
+	"cluely/internal/config"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestHintRingSince(t *testing.T) {
+	r := newHintRing(10)
+	for i := 1; i <= 6; i++ {
+		r.add(&HintMessage{ID: fmt.Sprint(i)})
+	}
+
+	tests := []struct {
+		name  string
+		since uint64
+		epoch string
+		want  []uint64
+	}{
+		{name: "same epoch", since: 4, epoch: r.epoch, want: []uint64{5, 6}},
+		{name: "up to date", since: 6, epoch: r.epoch, want: nil},
+		{name: "previous run with smaller seq", since: 5, epoch: "old", want: []uint64{1, 2, 3, 4, 5, 6}},
+		{name: "previous run with larger seq", since: 9, epoch: "old", want: []uint64{1, 2, 3, 4, 5, 6}},
+		{name: "no epoch", since: 4, want: []uint64{5, 6}},
+		{name: "no epoch, seq from the future", since: 9, want: []uint64{1, 2, 3, 4, 5, 6}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []uint64
+			for _, message := range r.since(tt.since, tt.epoch) {
+				got = append(got, message.Seq)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("since(%d, %q) = %v, want %v", tt.since, tt.epoch, got, tt.want)
+			}
+		})
+	}
+}
+
+// connectSince подключается к серверу UI и возвращает info и подсказки из replay
+func connectSince(t *testing.T, server *httptest.Server, since uint64, epoch string) (InfoMessage, []HintMessage) {
+	t.Helper()
+	query := url.Values{"since": {fmt.Sprint(since)}, "epoch": {epoch}}
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws?"+query.Encode(), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	var info InfoMessage
+	var hints []HintMessage
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		switch kind, _ := messageType(t, data); kind {
+		case TypeInfo:
+			json.Unmarshal(data, &info)
+		case TypeHint:
+			var hint HintMessage
+			json.Unmarshal(data, &hint)
+			hints = append(hints, hint)
+		}
+	}
+	return info, hints
+}
+
+func TestReplayAfterAgentRestart(t *testing.T) {
+	sendHints := func(s *Server, prefix string, count int) {
+		for i := 1; i <= count; i++ {
+			s.SendHint(fmt.Sprintf("%s%d", prefix, i), ai.AnalysisInput{Type: "audio"}, ai.AnalysisOutput{Hint: prefix})
+		}
+	}
+
+	old := NewServer(config.UIConfig{Enabled: true})
+	oldServer := httptest.NewServer(http.HandlerFunc(old.handleWebSocket))
+	sendHints(old, "old", 5)
+	info, hints := connectSince(t, oldServer, 0, "")
+	oldServer.Close()
+	if len(hints) != 5 || info.Seq != 5 || info.Epoch == "" {
+		t.Fatalf("first connection: info %+v, %d hints", info, len(hints))
+	}
+
+	// Новый запуск агента успел выдать столько же подсказок, сколько клиент видел в прошлом
+	restarted := NewServer(config.UIConfig{Enabled: true})
+	server := httptest.NewServer(http.HandlerFunc(restarted.handleWebSocket))
+	defer server.Close()
+	sendHints(restarted, "new", 7)
+
+	newInfo, replayed := connectSince(t, server, info.Seq, info.Epoch)
+	if newInfo.Epoch == info.Epoch {
+		t.Fatalf("epoch did not change across restart: %q", info.Epoch)
+	}
+	if len(replayed) != 7 || replayed[0].ID != "new1" {
+		t.Errorf("replayed %d hints after restart, want all 7 from the new run", len(replayed))
+	}
+
+	// В том же запуске приходят только пропущенные
+	if _, replayed := connectSince(t, server, 5, newInfo.Epoch); len(replayed) != 2 {
+		t.Errorf("replayed %d hints within the epoch, want 2", len(replayed))
+	}
+}
+
+func TestReplayNotSentToRejectedClients(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    config.UIConfig
+		query  string
+		header http.Header
+	}{
+		{name: "foreign origin", cfg: config.UIConfig{Enabled: true}, header: http.Header{"Origin": {"https://evil.example"}}},
+		{name: "missing token", cfg: config.UIConfig{Enabled: true, ListenAddress: "0.0.0.0", AuthToken: "s3cret"}},
+		{name: "wrong token", cfg: config.UIConfig{Enabled: true, ListenAddress: "0.0.0.0", AuthToken: "s3cret"}, query: "?token=guess"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(tt.cfg)
+			server := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+			defer server.Close()
+			s.SendHint("1", ai.AnalysisInput{Type: "vision", OCRText: "db password rotated"}, ai.AnalysisOutput{Hint: "check"})
+
+			conn, _, err := dialWebSocket(server, tt.query, tt.header)
+			if err == nil {
+				conn.Close()
+				t.Fatal("handshake succeeded")
+			}
+			if m := s.Metrics(); m.ClientsTotal != 0 || m.MessagesSent != 0 {
+				t.Errorf("rejected client got messages: %+v", m)
+			}
+		})
+	}
+}
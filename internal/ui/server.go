@@ -1,11 +1,13 @@
 package ui
 
 import (
-	"fmt"
+	"encoding/json"
 	"log"
+	"net"
 	"net/http"
-	"sync"
+	"strconv"
 
+	"cluely/internal/ai"
 	"cluely/internal/config"
 
 	"github.com/gorilla/websocket"
@@ -13,19 +15,35 @@ import (
 
 type Server struct {
 	cfg      config.UIConfig
-	clients  []*websocket.Conn
+	hub      *hub
+	hints    *hintRing
+	status   StatusMessage // последнее состояние пауз; защищено hints.mu
+	commands chan Command
 	upgrader websocket.Upgrader
-	mu       sync.Mutex
 }
 
 func NewServer(cfg config.UIConfig) *Server {
-	return &Server{
-		cfg:     cfg,
-		clients: make([]*websocket.Conn, 0),
-		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool { return true },
+	hints := newHintRing(cfg.MaxMessages)
+
+	// Очередь клиента должна вмещать приветствие, состояние и весь replay: до max обычных
+	// и до max закрепленных подсказок
+	queueSize := cfg.SendQueueSize
+	if queueSize <= 0 {
+		queueSize = defaultSendQueueSize
+	}
+	queueSize = max(queueSize, 2*hints.max+2)
+
+	s := &Server{
+		cfg:   cfg,
+		hub:   newHub(queueSize, cfg.SlowClientMaxDrops),
+		hints: hints,
+		status: StatusMessage{
+			Header: newHeader(TypeStatus),
 		},
+		commands: make(chan Command, commandQueueSize),
 	}
+	s.upgrader = websocket.Upgrader{CheckOrigin: s.checkOrigin}
+	return s
 }
 
 func (s *Server) Start() error {
@@ -33,14 +51,21 @@ func (s *Server) Start() error {
 		log.Println("⏭️  UI Server disabled")
 		return nil
 	}
+	if err := s.checkAccess(); err != nil {
+		return err
+	}
 
 	http.HandleFunc("/ws", s.handleWebSocket)
 	http.HandleFunc("/", s.handleIndex)
 	http.HandleFunc("/health", s.handleHealth)
+	http.HandleFunc("/metrics", s.handleMetrics)
 
-	addr := fmt.Sprintf(":%d", s.cfg.Port)
+	addr := net.JoinHostPort(s.listenAddress(), strconv.Itoa(s.cfg.Port))
+	if !isLoopbackHost(s.listenAddress()) {
+		log.Printf("⚠️  UI server is reachable from the network on %s, clients need ui.auth_token", addr)
+	}
 	go func() {
-		log.Printf("🌐 UI server listening on http://localhost%s", addr)
+		log.Printf("🌐 UI server listening on http://%s", addr)
 		if err := http.ListenAndServe(addr, nil); err != nil {
 			log.Printf("❌ UI server error: %v", err)
 		}
@@ -49,24 +74,56 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// Handle монтирует дополнительный обработчик на HTTP сервер UI (например, загрузку скриншотов).
+// Обработчик получает только запросы, прошедшие те же проверки доступа, что и WebSocket.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	http.Handle(pattern, s.protect(handler))
+	log.Printf("🌐 UI route registered: %s", pattern)
+}
+
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("❌ WebSocket upgrade error: %v", err)
 		return
 	}
 
-	s.mu.Lock()
-	s.clients = append(s.clients, conn)
-	s.mu.Unlock()
+	// ?since=N&epoch=E - последний номер подсказки, который клиент уже видел, и запуск агента,
+	// в котором он его видел
+	since, _ := strconv.ParseUint(r.URL.Query().Get("since"), 10, 64)
+	epoch := r.URL.Query().Get("epoch")
 
-	log.Println("✅ New WebSocket client connected")
+	c := newClient(s.hub, conn, s.handleMessage)
 
-	// Отправляем приветствие
-	s.sendToClient(conn, map[string]interface{}{
-		"type": "info",
-		"data": "Connected to Cluely",
+	s.hints.mu.Lock()
+	s.hub.register(c)
+
+	// Отправляем приветствие и подсказки, пропущенные клиентом
+	s.hub.send(c, InfoMessage{
+		Header:      newHeader(TypeInfo),
+		Message:     "Connected to Cluely",
+		Seq:         s.hints.seq,
+		Epoch:       s.hints.epoch,
+		MaxMessages: s.hints.max,
 	})
+	s.hub.send(c, s.status)
+	missed := s.hints.since(since, epoch)
+	for _, message := range missed {
+		s.hub.send(c, message)
+	}
+	s.hints.mu.Unlock()
+
+	if len(missed) > 0 {
+		log.Printf("🔁 Replayed %d hints to %s (since #%d)", len(missed), c.addr, since)
+	}
+
+	go c.writePump()
+	go c.readPump()
 }
 
 func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
@@ -95,6 +152,13 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
             border-radius: 4px;
             animation: slideIn 0.3s ease;
         }
+        .hint.streaming {
+            border-left-color: #888;
+        }
+        .hint.failed {
+            border-left-color: #ff4444;
+            opacity: 0.7;
+        }
         @keyframes slideIn {
             from { opacity: 0; transform: translateX(-20px); }
             to { opacity: 1; transform: translateX(0); }
@@ -103,47 +167,341 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
             color: #00ff88;
             margin-bottom: 20px;
         }
+        .caption {
+            max-width: 800px;
+            margin: 0 auto 10px;
+            color: #aaa;
+            font-style: italic;
+            min-height: 1.2em;
+        }
         h1 { color: #00ff88; }
         .timestamp {
             color: #888;
             font-size: 12px;
         }
+        .badge {
+            display: inline-block;
+            margin-left: 6px;
+            padding: 0 6px;
+            border-radius: 3px;
+            background: #3a3a3a;
+            color: #ccc;
+        }
+        .tasks, .warnings {
+            list-style: none;
+            padding: 0;
+            margin: 10px 0 0;
+        }
+        .tasks label {
+            cursor: pointer;
+        }
+        .tasks input:checked + span {
+            text-decoration: line-through;
+            color: #888;
+        }
+        .warnings li {
+            background: #4a3000;
+            border-left: 3px solid #ffaa00;
+            color: #ffd580;
+            padding: 4px 8px;
+            margin: 4px 0;
+        }
+        .input {
+            color: #888;
+            font-size: 12px;
+            margin-top: 10px;
+            white-space: pre-wrap;
+        }
+        .hint.pinned {
+            border-left-color: #ffaa00;
+        }
+        .controls {
+            max-width: 800px;
+            margin: 0 auto 10px;
+            display: flex;
+            gap: 6px;
+        }
+        .controls input {
+            flex: 1;
+        }
+        button, .controls input {
+            background: #2a2a2a;
+            color: #fff;
+            border: 1px solid #444;
+            border-radius: 4px;
+            padding: 4px 8px;
+        }
+        button:hover {
+            border-color: #00ff88;
+        }
+        button.active {
+            border-color: #ffaa00;
+            color: #ffaa00;
+        }
+        .actions {
+            margin-top: 10px;
+        }
+        .actions button {
+            font-size: 12px;
+        }
     </style>
 </head>
 <body>
     <h1>🤖 Cluely AI Assistant</h1>
     <div class="status" id="status">Connecting...</div>
+    <div class="controls">
+        <button id="pause-audio">⏸️ Audio</button>
+        <button id="pause-vision">⏸️ Screen</button>
+        <button id="capture">📸 Capture</button>
+        <input id="question" placeholder="Ask a question...">
+        <button id="ask">Ask</button>
+    </div>
+    <div class="caption" id="caption"></div>
     <div id="hints"></div>
     
     <script>
-        const ws = new WebSocket('ws://' + window.location.host + '/ws');
+        // Формат сообщений описан в docs/websocket-protocol.md
+        const SCHEMA_VERSION = 1;
+        const SOURCES = { audio: '🎤 audio', vision: '📸 screen', combined: '🔗 audio + screen', question: '💬 question' };
+
         const status = document.getElementById('status');
         const hints = document.getElementById('hints');
-        
-        ws.onopen = () => {
-            status.textContent = '✅ Connected';
-        };
-        
-        ws.onclose = () => {
-            status.textContent = '❌ Disconnected';
-        };
-        
-        ws.onmessage = (event) => {
-            const msg = JSON.parse(event.data);
-            
-            if (msg.type === 'hint') {
-                const hint = document.createElement('div');
-                hint.className = 'hint';
-                const time = new Date().toLocaleTimeString();
-                hint.innerHTML = '<div class="timestamp">' + time + '</div>' + msg.data;
-                hints.insertBefore(hint, hints.firstChild);
-                
-                // Ограничиваем количество сообщений
-                while (hints.children.length > 10) {
-                    hints.removeChild(hints.lastChild);
+        const caption = document.getElementById('caption');
+        const pauseAudio = document.getElementById('pause-audio');
+        const pauseVision = document.getElementById('pause-vision');
+        const question = document.getElementById('question');
+
+        // Лимит приходит от сервера (ui.max_messages) в приветствии
+        let maxMessages = 10;
+        // Номер последней полученной подсказки и запуск агента, к которому он относится:
+        // при переподключении сервер пришлет только пропущенные
+        let lastSeq = 0;
+        let epoch = '';
+        let ws = null;
+        let paused = { audio: false, vision: false };
+        // Команды, ждущие подтверждения, по ID
+        let commandSeq = 0;
+        const pending = {};
+        // ui.auth_token, если страница открыта как /?token=...
+        const token = new URLSearchParams(window.location.search).get('token');
+
+        function connect() {
+            ws = new WebSocket('ws://' + window.location.host + '/ws?since=' + lastSeq + '&epoch=' + encodeURIComponent(epoch) +
+                (token ? '&token=' + encodeURIComponent(token) : ''));
+
+            ws.onopen = () => {
+                status.textContent = '✅ Connected';
+            };
+
+            ws.onclose = () => {
+                status.textContent = '❌ Disconnected, reconnecting...';
+                setTimeout(connect, 2000);
+            };
+
+            ws.onmessage = (event) => {
+                const msg = JSON.parse(event.data);
+                if (msg.v > SCHEMA_VERSION) {
+                    console.warn('Unsupported message schema version', msg.v);
+                }
+
+                if (msg.type === 'info') {
+                    maxMessages = msg.max_messages || maxMessages;
+                    if (msg.epoch !== epoch) {
+                        // Агент перезапущен - старые подсказки придут заново
+                        hints.innerHTML = '';
+                        lastSeq = 0;
+                        epoch = msg.epoch;
+                    }
+                    trimHints();
+                } else if (msg.type === 'status') {
+                    paused = { audio: msg.audio_paused, vision: msg.vision_paused };
+                    renderStatus();
+                } else if (msg.type === 'ack') {
+                    const done = pending[msg.id];
+                    delete pending[msg.id];
+                    if (!msg.ok) {
+                        status.textContent = '⚠️ ' + msg.command + ': ' + msg.error;
+                    } else if (done) {
+                        done(msg);
+                    }
+                } else if (msg.type === 'caption') {
+                    caption.textContent = msg.text ? '🎤 ' + msg.text : '';
+                } else if (msg.type === 'transcript') {
+                    caption.textContent = '🎤 ' + (msg.speaker ? msg.speaker + ': ' : '') + msg.text;
+                } else if (msg.type === 'partial') {
+                    const hint = findHint(msg);
+                    hint.querySelector('.text').textContent = msg.hint;
+                    hint.classList.add('streaming');
+                } else if (msg.type === 'hint') {
+                    renderHint(findHint(msg), msg);
+                    lastSeq = msg.seq;
+                } else if (msg.type === 'error') {
+                    const hint = document.getElementById('hint-' + msg.id);
+                    if (hint) {
+                        failHint(hint, msg);
+                    }
+                } else if (msg.type === 'pin') {
+                    const hint = document.getElementById('hint-' + msg.hint_id);
+                    if (hint) {
+                        setPinned(hint, msg.pinned);
+                    }
                 }
+            };
+        }
+
+        // send отправляет команду агенту; onAck вызывается при успешном подтверждении
+        function send(command, fields, onAck) {
+            if (!ws || ws.readyState !== WebSocket.OPEN) {
+                status.textContent = '⚠️ Not connected';
+                return;
+            }
+            const id = 'c-' + (++commandSeq);
+            if (onAck) {
+                pending[id] = onAck;
+            }
+            ws.send(JSON.stringify(Object.assign({ v: SCHEMA_VERSION, type: 'command', id: id, command: command }, fields)));
+        }
+
+        pauseAudio.onclick = () => send(paused.audio ? 'resume' : 'pause', { target: 'audio' });
+        pauseVision.onclick = () => send(paused.vision ? 'resume' : 'pause', { target: 'vision' });
+        document.getElementById('capture').onclick = () => send('capture', {}, () => {
+            status.textContent = '📸 Captured';
+        });
+
+        function ask() {
+            const text = question.value.trim();
+            if (text) {
+                send('ask', { text: text });
+                question.value = '';
+            }
+        }
+        document.getElementById('ask').onclick = ask;
+        question.onkeydown = (event) => {
+            if (event.key === 'Enter') {
+                ask();
             }
         };
+
+        function renderStatus() {
+            pauseAudio.textContent = paused.audio ? '▶️ Audio' : '⏸️ Audio';
+            pauseAudio.classList.toggle('active', paused.audio);
+            pauseVision.textContent = paused.vision ? '▶️ Screen' : '⏸️ Screen';
+            pauseVision.classList.toggle('active', paused.vision);
+        }
+
+        function findHint(msg) {
+            return document.getElementById('hint-' + msg.id) || addHint('hint-' + msg.id, msg);
+        }
+
+        function addHint(id, msg) {
+            const hint = document.createElement('div');
+            hint.className = 'hint';
+            hint.id = id;
+            hint.innerHTML = '<div class="timestamp"></div><div class="text"></div>' +
+                '<ul class="warnings"></ul><ul class="tasks"></ul><div class="input"></div><div class="actions"></div>';
+
+            const timestamp = hint.querySelector('.timestamp');
+            timestamp.textContent = new Date(msg.time).toLocaleTimeString();
+            const badge = document.createElement('span');
+            badge.className = 'badge';
+            badge.textContent = SOURCES[msg.source] || msg.source;
+            timestamp.appendChild(badge);
+
+            hints.insertBefore(hint, hints.firstChild);
+            trimHints();
+            return hint;
+        }
+
+        function renderHint(hint, msg) {
+            hint.classList.remove('streaming');
+            hint.querySelector('.text').textContent = msg.hint;
+
+            const meta = document.createElement('span');
+            meta.className = 'badge';
+            meta.textContent = msg.provider + ' · ' + Math.round(msg.confidence * 100) + '%';
+            hint.querySelector('.timestamp').appendChild(meta);
+
+            const warnings = hint.querySelector('.warnings');
+            warnings.innerHTML = '';
+            for (const warning of msg.warnings) {
+                const item = document.createElement('li');
+                item.textContent = '⚠️ ' + warning;
+                warnings.appendChild(item);
+            }
+
+            const tasks = hint.querySelector('.tasks');
+            tasks.innerHTML = '';
+            for (const task of msg.tasks) {
+                const item = document.createElement('li');
+                item.innerHTML = '<label><input type="checkbox"> <span></span></label>';
+                item.querySelector('span').textContent = task;
+                tasks.appendChild(item);
+            }
+
+            const input = msg.input || {};
+            const parts = [];
+            if (input.question) {
+                parts.push('💬 ' + input.question);
+            }
+            if (input.transcript) {
+                parts.push('🎤 ' + (input.speaker ? input.speaker + ': ' : '') + input.transcript);
+            }
+            if (input.screen) {
+                parts.push('📸 ' + (input.app ? input.app + ': ' : '') + input.screen);
+            }
+            if (input.note) {
+                parts.push('📝 ' + input.note);
+            }
+            hint.querySelector('.input').textContent = parts.join('\n');
+
+            renderActions(hint, msg.id);
+            setPinned(hint, msg.pinned);
+        }
+
+        // failHint завершает потоковую подсказку, для которой анализ не удался
+        function failHint(hint, msg) {
+            hint.classList.remove('streaming');
+            hint.classList.add('failed');
+            const error = document.createElement('li');
+            error.textContent = '❌ Analysis failed: ' + msg.error;
+            hint.querySelector('.warnings').appendChild(error);
+        }
+
+        function renderActions(hint, id) {
+            const actions = hint.querySelector('.actions');
+            actions.innerHTML = '<button class="useful">👍 Useful</button> <button class="wrong">👎 Wrong</button> <button class="pin">📌 Pin</button>';
+
+            const rate = (value) => send('feedback', { hint_id: id, value: value }, () => {
+                actions.querySelector('.useful').disabled = true;
+                actions.querySelector('.wrong').disabled = true;
+                actions.querySelector('.' + value).classList.add('active');
+            });
+            actions.querySelector('.useful').onclick = () => rate('useful');
+            actions.querySelector('.wrong').onclick = () => rate('wrong');
+            actions.querySelector('.pin').onclick = () => {
+                send(hint.classList.contains('pinned') ? 'unpin' : 'pin', { hint_id: id });
+            };
+        }
+
+        function setPinned(hint, pinned) {
+            hint.classList.toggle('pinned', pinned);
+            const button = hint.querySelector('.pin');
+            if (button) {
+                button.textContent = pinned ? '📌 Unpin' : '📌 Pin';
+                button.classList.toggle('active', pinned);
+            }
+            trimHints();
+        }
+
+        // Ограничиваем количество сообщений; закрепленные подсказки не удаляются
+        function trimHints() {
+            const unpinned = Array.from(hints.children).filter((hint) => !hint.classList.contains('pinned'));
+            while (unpinned.length > maxMessages) {
+                hints.removeChild(unpinned.pop());
+            }
+        }
+
+        connect();
     </script>
 </body>
 </html>`
@@ -157,36 +515,92 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"status":"ok"}`))
 }
 
-func (s *Server) SendHint(hint string) {
-	message := map[string]interface{}{
-		"type": "hint",
-		"data": hint,
-	}
+// handleMetrics отдает счетчики клиентов и отброшенных сообщений
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.hub.metrics())
+}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// Metrics возвращает текущие счетчики WebSocket клиентов
+func (s *Server) Metrics() Metrics {
+	return s.hub.metrics()
+}
 
-	for i := len(s.clients) - 1; i >= 0; i-- {
-		if err := s.clients[i].WriteJSON(message); err != nil {
-			log.Printf("⚠️  Error sending to client: %v", err)
-			s.clients[i].Close()
-			s.clients = append(s.clients[:i], s.clients[i+1:]...)
-		}
-	}
+// SendCaption отправляет промежуточный результат распознавания речи (живые субтитры)
+func (s *Server) SendCaption(text string) {
+	s.hub.broadcastLatest(captionKey, CaptionMessage{
+		Header: newHeader(TypeCaption),
+		Text:   text,
+	})
 }
 
-func (s *Server) sendToClient(conn *websocket.Conn, message interface{}) {
-	if err := conn.WriteJSON(message); err != nil {
-		log.Printf("⚠️  Error sending to client: %v", err)
-	}
+// SendTranscript отправляет завершенную реплику с меткой говорящего; она заменяет живой субтитр
+func (s *Server) SendTranscript(speaker, text string) {
+	s.hub.broadcast(TranscriptMessage{
+		Header:  newHeader(TypeTranscript),
+		Speaker: speaker,
+		Text:    text,
+	}, captionKey)
 }
 
-func (s *Server) Stop() {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// SendPartialHint отправляет накопленный на данный момент текст подсказки id.
+// Клиент, не успевший получить предыдущий вариант, получит только этот.
+func (s *Server) SendPartialHint(id, source, text string) {
+	s.hub.broadcastLatest(partialKey(id), PartialHintMessage{
+		Header: newHeader(TypePartialHint),
+		ID:     id,
+		Source: source,
+		Hint:   text,
+	})
+}
+
+// SendHint завершает потоковую подсказку id и передает полный результат анализа
+// вместе с фрагментом ввода, по которому она построена
+func (s *Server) SendHint(id string, input ai.AnalysisInput, output ai.AnalysisOutput) {
+	s.broadcastHint(&HintMessage{
+		Header:     newHeader(TypeHint),
+		ID:         id,
+		Source:     input.Type,
+		Hint:       output.Hint,
+		Tasks:      nonNil(output.Tasks),
+		Warnings:   nonNil(output.Warnings),
+		Confidence: output.Confidence,
+		Provider:   output.Provider,
+		Input:      newInputExcerpt(input),
+	})
+}
+
+// SendHintError сообщает, что анализ для подсказки id не удался, чтобы страница
+// завершила ее потоковый вариант
+func (s *Server) SendHintError(id, source string, err error) {
+	s.hub.broadcast(HintErrorMessage{
+		Header: newHeader(TypeHintError),
+		ID:     id,
+		Source: source,
+		Error:  err.Error(),
+	}, partialKey(id))
+}
 
-	for _, client := range s.clients {
-		client.Close()
+// Ключи промежуточных сообщений hub.broadcastLatest
+const captionKey = "caption"
+
+func partialKey(id string) string {
+	return "partial/" + id
+}
+
+// broadcastHint нумерует итоговую подсказку, сохраняет ее для replay и рассылает клиентам
+func (s *Server) broadcastHint(message *HintMessage) {
+	s.hints.mu.Lock()
+	defer s.hints.mu.Unlock()
+
+	s.hints.add(message)
+	s.hub.broadcastReplayable(message, partialKey(message.ID))
+}
+
+func (s *Server) Stop() {
+	m := s.hub.metrics()
+	s.hub.closeAll()
+	if m.MessagesDropped > 0 {
+		log.Printf("📉 UI dropped %d messages, disconnected %d slow clients", m.MessagesDropped, m.SlowClientsDropped)
 	}
-	s.clients = nil
 }
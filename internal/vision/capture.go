@@ -0,0 +1,91 @@
+package vision
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// captureTimeout ограничивает время работы vision.capture_command
+const captureTimeout = 10 * time.Second
+
+// CaptureRequest - запрос немедленного снимка экрана вне расписания источника
+type CaptureRequest struct {
+	Trigger string // что вызвало снимок: имя правила, "hotkey", "focus"
+	Note    string // комментарий пользователя к снимку
+	App     string // приложение в фокусе, если известно
+}
+
+// Capture делает снимок по запросу и ставит его в очередь скриншотов.
+// Снимок берется командой vision.capture_command (PNG/JPEG в stdout), а без нее
+// в mock режиме - синтетическим кадром. Пассивные источники (dir, upload) сами снимать не умеют.
+func (m *Module) Capture(ctx context.Context, req CaptureRequest) error {
+	m.mu.Lock()
+	running := m.isRunning
+	m.mu.Unlock()
+	if !running {
+		return errors.New("vision module is not running")
+	}
+	if m.paused.Load() {
+		return errors.New("screen capture is paused")
+	}
+
+	app := req.App
+	if app == "" {
+		app = m.FocusedApp()
+	}
+	if app == "" {
+		app = m.cfg.SourceApp
+	}
+
+	var shot Screenshot
+	switch {
+	case len(m.cfg.CaptureCommand) > 0:
+		data, err := runCaptureCommand(ctx, m.cfg.CaptureCommand)
+		if err != nil {
+			return err
+		}
+		shot, err = newScreenshot(data, "command", app, time.Now())
+		if err != nil {
+			return err
+		}
+	case m.source == nil:
+		shot = m.mockScreenshot(app)
+	default:
+		return fmt.Errorf("screenshot source %q cannot capture on demand, set vision.capture_command", m.cfg.Source)
+	}
+
+	shot.Trigger = req.Trigger
+	shot.Note = req.Note
+	if !m.publish(shot) {
+		return errors.New("screenshot queue is not accepting frames")
+	}
+
+	log.Printf("📸 Capture triggered by %s (%s %dx%d, app %q)", req.Trigger, shot.Format, shot.Width, shot.Height, shot.App)
+	return nil
+}
+
+// runCaptureCommand запускает команду снимка экрана и возвращает изображение из stdout,
+// например ["grim", "-"] или ["import", "-window", "root", "png:-"]
+func runCaptureCommand(ctx context.Context, command []string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, captureTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("capture command %q failed: %w: %s", command[0], err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("capture command %q wrote no image to stdout", command[0])
+	}
+	return stdout.Bytes(), nil
+}
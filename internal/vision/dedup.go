@@ -0,0 +1,243 @@
+package vision
+
+import (
+	"image"
+	"math/bits"
+	"sync"
+)
+
+const (
+	// dHash строится по миниатюре 9x8: 8 сравнений соседних пикселей в каждой из 8 строк
+	dHashWidth  = 9
+	dHashHeight = 8
+	// tileThumbSize - сторона миниатюры одной ячейки сетки для поиска изменившихся областей
+	tileThumbSize = 16
+	// tileDiffThreshold - ячейка считается изменившейся, если яркость хотя бы одной точки
+	// ее миниатюры отличается больше чем на это значение (0-255)
+	tileDiffThreshold = 24
+	// fullFrameRatio - если изменилось больше этой доли кадра, распознается весь кадр
+	fullFrameRatio = 0.5
+	// maxSamples - сколько пикселей на сторону ячейки миниатюры усредняется, чтобы большие
+	// скриншоты не замедляли хеширование
+	maxSamples = 16
+)
+
+// frameSignature - отпечаток кадра: perceptual hash и миниатюры ячеек сетки
+type frameSignature struct {
+	hash  uint64
+	size  image.Point
+	tiles []uint8 // яркость сетки grid*tileThumbSize x grid*tileThumbSize
+}
+
+// frameDeduper отбрасывает кадры, почти совпадающие с предыдущим кадром того же источника,
+// и находит изменившиеся области для частичного OCR
+type frameDeduper struct {
+	distance int // максимальное расстояние Хэмминга для дубликата; < 0 - дедупликация выключена
+	grid     int // размер сетки изменившихся областей; <= 1 - всегда весь кадр
+
+	mu   sync.Mutex
+	last map[string]frameSignature
+}
+
+func newFrameDeduper(distance, grid int) *frameDeduper {
+	return &frameDeduper{
+		distance: distance,
+		grid:     grid,
+		last:     make(map[string]frameSignature),
+	}
+}
+
+// Check сравнивает кадр с предыдущим кадром источника key. Возвращает отпечаток нового кадра,
+// признак дубликата, расстояние Хэмминга и изменившиеся области (nil - распознавать весь кадр).
+// dHash 8x8 почти не реагирует на смену текста в терминале, поэтому при включенной сетке
+// дубликатом считается только кадр без изменившихся ячеек.
+// Отпечаток запоминается отдельно через Remember, когда кадр действительно принят в обработку.
+func (d *frameDeduper) Check(key string, img image.Image) (frameSignature, bool, int, []image.Rectangle) {
+	sig := d.signature(img)
+
+	d.mu.Lock()
+	prev, ok := d.last[key]
+	d.mu.Unlock()
+
+	if !ok || prev.size != sig.size {
+		return sig, false, -1, nil
+	}
+
+	distance := bits.OnesCount64(prev.hash ^ sig.hash)
+	changed, count := d.changedTiles(prev, sig)
+	if d.distance >= 0 && distance <= d.distance && count == 0 {
+		return sig, true, distance, nil
+	}
+
+	// Хеш отличается, но по ячейкам разница ниже порога, либо изменилась большая часть кадра -
+	// распознаем кадр целиком
+	if count == 0 || float64(count) > float64(d.grid*d.grid)*fullFrameRatio {
+		return sig, false, distance, nil
+	}
+
+	return sig, false, distance, d.regions(img.Bounds(), changed)
+}
+
+// Remember сохраняет отпечаток как предыдущий кадр источника key
+func (d *frameDeduper) Remember(key string, sig frameSignature) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.last[key] = sig
+}
+
+func (d *frameDeduper) signature(img image.Image) frameSignature {
+	sig := frameSignature{
+		hash: dHash(img),
+		size: img.Bounds().Size(),
+	}
+	if d.grid > 1 {
+		side := d.grid * tileThumbSize
+		sig.tiles = grayThumbnail(img, side, side)
+	}
+	return sig
+}
+
+// dHash - разностный хеш: бит равен 1, если точка миниатюры ярче соседней справа
+func dHash(img image.Image) uint64 {
+	thumb := grayThumbnail(img, dHashWidth, dHashHeight)
+
+	var hash uint64
+	for y := 0; y < dHashHeight; y++ {
+		for x := 0; x < dHashWidth-1; x++ {
+			hash <<= 1
+			if thumb[y*dHashWidth+x] > thumb[y*dHashWidth+x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// changedTiles отмечает ячейки сетки, в которых кадр изменился, и возвращает их число
+func (d *frameDeduper) changedTiles(prev, next frameSignature) ([]bool, int) {
+	if d.grid <= 1 || len(prev.tiles) != len(next.tiles) {
+		return nil, 0
+	}
+
+	side := d.grid * tileThumbSize
+	changed := make([]bool, d.grid*d.grid)
+	count := 0
+	for ty := 0; ty < d.grid; ty++ {
+		for tx := 0; tx < d.grid; tx++ {
+			if tileChanged(prev.tiles, next.tiles, side, tx, ty) {
+				changed[ty*d.grid+tx] = true
+				count++
+			}
+		}
+	}
+	return changed, count
+}
+
+// regions объединяет соседние изменившиеся ячейки в прямоугольники
+// и переводит их в координаты изображения с небольшим запасом по краям
+func (d *frameDeduper) regions(bounds image.Rectangle, changed []bool) []image.Rectangle {
+	tileW := bounds.Dx() / d.grid
+	tileH := bounds.Dy() / d.grid
+	margin := min(tileW, tileH) / 4
+
+	var regions []image.Rectangle
+	for _, component := range connectedTiles(changed, d.grid) {
+		region := image.Rect(
+			bounds.Min.X+component.Min.X*tileW-margin,
+			bounds.Min.Y+component.Min.Y*tileH-margin,
+			bounds.Min.X+component.Max.X*tileW+margin,
+			bounds.Min.Y+component.Max.Y*tileH+margin,
+		)
+		// Последняя ячейка забирает остаток от деления размера кадра на сетку
+		if component.Max.X == d.grid {
+			region.Max.X = bounds.Max.X
+		}
+		if component.Max.Y == d.grid {
+			region.Max.Y = bounds.Max.Y
+		}
+		regions = append(regions, region.Intersect(bounds))
+	}
+	return regions
+}
+
+func tileChanged(prev, next []uint8, side, tx, ty int) bool {
+	for y := ty * tileThumbSize; y < (ty+1)*tileThumbSize; y++ {
+		for x := tx * tileThumbSize; x < (tx+1)*tileThumbSize; x++ {
+			i := y*side + x
+			diff := int(prev[i]) - int(next[i])
+			if diff > tileDiffThreshold || diff < -tileDiffThreshold {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// connectedTiles объединяет соседние (по стороне) изменившиеся ячейки и возвращает
+// ограничивающие прямоугольники групп в координатах сетки
+func connectedTiles(changed []bool, grid int) []image.Rectangle {
+	visited := make([]bool, len(changed))
+	var components []image.Rectangle
+
+	for start := range changed {
+		if !changed[start] || visited[start] {
+			continue
+		}
+
+		bounds := image.Rect(start%grid, start/grid, start%grid+1, start/grid+1)
+		stack := []int{start}
+		visited[start] = true
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := i%grid, i/grid
+			bounds = bounds.Union(image.Rect(x, y, x+1, y+1))
+
+			for _, n := range [][2]int{{x - 1, y}, {x + 1, y}, {x, y - 1}, {x, y + 1}} {
+				if n[0] < 0 || n[0] >= grid || n[1] < 0 || n[1] >= grid {
+					continue
+				}
+				j := n[1]*grid + n[0]
+				if changed[j] && !visited[j] {
+					visited[j] = true
+					stack = append(stack, j)
+				}
+			}
+		}
+		components = append(components, bounds)
+	}
+	return components
+}
+
+// grayThumbnail уменьшает изображение до width x height точек яркости, усредняя
+// до maxSamples x maxSamples пикселей на точку
+func grayThumbnail(img image.Image, width, height int) []uint8 {
+	bounds := img.Bounds()
+	thumb := make([]uint8, width*height)
+	if bounds.Empty() {
+		return thumb
+	}
+
+	for ty := 0; ty < height; ty++ {
+		y0 := bounds.Min.Y + ty*bounds.Dy()/height
+		y1 := max(bounds.Min.Y+(ty+1)*bounds.Dy()/height, y0+1)
+		stepY := max((y1-y0)/maxSamples, 1)
+
+		for tx := 0; tx < width; tx++ {
+			x0 := bounds.Min.X + tx*bounds.Dx()/width
+			x1 := max(bounds.Min.X+(tx+1)*bounds.Dx()/width, x0+1)
+			stepX := max((x1-x0)/maxSamples, 1)
+
+			var sum, samples uint32
+			for y := y0; y < y1; y += stepY {
+				for x := x0; x < x1; x += stepX {
+					r, g, b, _ := img.At(x, y).RGBA()
+					sum += (299*r + 587*g + 114*b) / 1000 >> 8
+					samples++
+				}
+			}
+			thumb[ty*width+tx] = uint8(sum / samples)
+		}
+	}
+	return thumb
+}
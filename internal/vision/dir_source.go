@@ -0,0 +1,167 @@
+package vision
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultPollInterval = time.Second
+
+// DirectorySource следит за каталогом и отдает новые PNG/JPEG файлы как скриншоты.
+// Подходит для headless Linux: снимки кладет Flameshot, scrot или любой скрипт.
+//
+// Файлы в корне каталога помечаются приложением из vision.source_app, файлы во вложенном
+// каталоге - его именем (screenshots/grafana/1.png -> "grafana").
+// Каталог опрашивается раз в poll_interval_ms; файл отдается, когда его размер и время
+// изменения не менялись между двумя опросами, чтобы не читать недописанный снимок.
+// Файлы, лежавшие в каталоге до запуска, пропускаются.
+type DirectorySource struct {
+	dir      string
+	app      string
+	interval time.Duration
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// fileState - размер и время изменения файла при последнем опросе
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+func NewDirectorySource(dir, app string, interval time.Duration) *DirectorySource {
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	return &DirectorySource{
+		dir:      dir,
+		app:      app,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+func (d *DirectorySource) Run(ctx context.Context, emit func(Screenshot) bool) error {
+	if _, err := os.Stat(d.dir); err != nil {
+		return err
+	}
+
+	seen := d.scan()
+	pending := make(map[string]fileState)
+	log.Printf("📂 Watching %s for screenshots (%d existing files skipped)", d.dir, len(seen))
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-d.stopCh:
+			return nil
+		case <-ticker.C:
+		}
+
+		current := d.scan()
+		for path, state := range current {
+			if prev, ok := seen[path]; ok && prev == state {
+				continue
+			}
+			// Файл новый или еще пишется - ждем следующего опроса
+			if prev, ok := pending[path]; !ok || prev != state {
+				pending[path] = state
+				continue
+			}
+
+			delete(pending, path)
+			seen[path] = state
+			d.emitFile(path, state, emit)
+		}
+
+		// Забываем удаленные файлы, чтобы состояние не росло бесконечно
+		for path := range seen {
+			if _, ok := current[path]; !ok {
+				delete(seen, path)
+			}
+		}
+		for path := range pending {
+			if _, ok := current[path]; !ok {
+				delete(pending, path)
+			}
+		}
+	}
+}
+
+func (d *DirectorySource) emitFile(path string, state fileState, emit func(Screenshot) bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("❌ Screenshot read error: %v", err)
+		return
+	}
+
+	shot, err := newScreenshot(data, "dir", d.appFor(path), state.modTime)
+	if err != nil {
+		log.Printf("⚠️  Skipping %s: %v", path, err)
+		return
+	}
+	emit(shot)
+}
+
+// scan возвращает изображения в каталоге и во вложенных каталогах первого уровня
+func (d *DirectorySource) scan() map[string]fileState {
+	files := make(map[string]fileState)
+
+	var walk func(dir string, depth int)
+	walk = func(dir string, depth int) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			log.Printf("⚠️  Screenshot dir scan error: %v", err)
+			return
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if entry.IsDir() {
+				if depth == 0 {
+					walk(path, depth+1)
+				}
+				continue
+			}
+			if !isImageFile(entry.Name()) {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			files[path] = fileState{size: info.Size(), modTime: info.ModTime()}
+		}
+	}
+	walk(d.dir, 0)
+
+	return files
+}
+
+func (d *DirectorySource) appFor(path string) string {
+	if parent := filepath.Dir(path); filepath.Clean(parent) != filepath.Clean(d.dir) {
+		return filepath.Base(parent)
+	}
+	return d.app
+}
+
+func isImageFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".png", ".jpg", ".jpeg":
+		return true
+	}
+	return false
+}
+
+func (d *DirectorySource) Close() error {
+	d.stopOnce.Do(func() { close(d.stopCh) })
+	return nil
+}
@@ -0,0 +1,108 @@
+package vision
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// runDirectorySource запускает источник и возвращает канал с его кадрами
+func runDirectorySource(t *testing.T, source *DirectorySource) <-chan Screenshot {
+	t.Helper()
+	shots := make(chan Screenshot, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- source.Run(context.Background(), func(shot Screenshot) bool {
+			shots <- shot
+			return true
+		})
+	}()
+	t.Cleanup(func() {
+		source.Close()
+		if err := <-done; err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	})
+	// Даем источнику запомнить уже лежащие файлы
+	time.Sleep(30 * time.Millisecond)
+	return shots
+}
+
+func writeImage(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, testFrame(t, 8, 4).Data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDirectorySourceEmitsNewFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeImage(t, filepath.Join(dir, "old.png"))
+
+	shots := runDirectorySource(t, NewDirectorySource(dir, "terminal", 10*time.Millisecond))
+
+	writeImage(t, filepath.Join(dir, "new.png"))
+	writeImage(t, filepath.Join(dir, "grafana", "panel.PNG"))
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not an image"), 0o644)
+
+	apps := make(map[string]int)
+	timeout := time.After(2 * time.Second)
+	for len(apps) < 2 {
+		select {
+		case shot := <-shots:
+			if shot.Source != "dir" || shot.Format != "png" {
+				t.Errorf("screenshot = %s/%s", shot.Source, shot.Format)
+			}
+			apps[shot.App]++
+		case <-timeout:
+			t.Fatalf("got screenshots for %v, want terminal and grafana", apps)
+		}
+	}
+
+	// Старый файл, текст и уже отданные снимки повторно не приходят
+	select {
+	case shot := <-shots:
+		t.Errorf("unexpected screenshot from %q", shot.App)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if apps["terminal"] != 1 || apps["grafana"] != 1 {
+		t.Errorf("apps = %v", apps)
+	}
+}
+
+func TestDirectorySourceWaitsForStableFile(t *testing.T) {
+	dir := t.TempDir()
+	shots := runDirectorySource(t, NewDirectorySource(dir, "", 40*time.Millisecond))
+
+	// Снимок дописывается: размер меняется между опросами
+	path := filepath.Join(dir, "shot.png")
+	data := testFrame(t, 8, 4).Data
+	if err := os.WriteFile(path, data[:10], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case shot := <-shots:
+		if shot.Width != 8 || shot.Height != 4 {
+			t.Errorf("got %dx%d, want the complete image", shot.Width, shot.Height)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("complete file was not emitted")
+	}
+}
+
+func TestDirectorySourceMissingDir(t *testing.T) {
+	source := NewDirectorySource(filepath.Join(t.TempDir(), "missing"), "", 0)
+	if err := source.Run(context.Background(), func(Screenshot) bool { return true }); err == nil {
+		t.Error("Run on a missing directory succeeded")
+	}
+}
@@ -0,0 +1,56 @@
+package vision
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+)
+
+// FakeWindowWatcher берет заголовок активного окна из первой строки текстового файла,
+// а идентификатор окна - из второй; без второй строки идентификатором служит заголовок.
+// Позволяет проверить триггеры по фокусу на headless машине:
+//
+//	echo "Grafana - Payments dashboard" > /tmp/cluely-window
+//	printf 'Grafana - Checkout\n0x3a00007\n' > /tmp/cluely-window
+type FakeWindowWatcher struct {
+	path string
+}
+
+func NewFakeWindowWatcher(path string) *FakeWindowWatcher {
+	return &FakeWindowWatcher{path: path}
+}
+
+func (f *FakeWindowWatcher) Initialize() error {
+	log.Printf("🪟 Fake window watcher initialized (file: %s)", f.path)
+	return nil
+}
+
+func (f *FakeWindowWatcher) ActiveWindow(ctx context.Context) (WindowInfo, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return WindowInfo{}, nil
+	}
+	if err != nil {
+		return WindowInfo{}, err
+	}
+
+	title, rest, _ := strings.Cut(string(data), "\n")
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return WindowInfo{}, nil
+	}
+
+	id, _, _ := strings.Cut(rest, "\n")
+	id = strings.TrimSpace(id)
+	if id == "" {
+		// Заголовок служит идентификатором: смена текста в файле - смена окна
+		id = title
+	}
+	return WindowInfo{ID: id, Title: title}, nil
+}
+
+func (f *FakeWindowWatcher) Close() error {
+	return nil
+}
@@ -0,0 +1,289 @@
+package vision
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	// columnGapRatio - промежуток между словами шире средней ширины символа, умноженной
+	// на это число, считается границей колонки: один пробел слова не разделяет,
+	// два и больше (выравнивание в kubectl, top, df) - разделяют
+	columnGapRatio = 1.5
+	// minTableRows - столько подряд идущих строк с выровненными колонками образуют таблицу
+	minTableRows = 2
+)
+
+// layoutGap - свободная от слов полоса по горизонтали: промежуток между словами строки
+// или, для таблицы, промежуток, свободный во всех ее строках
+type layoutGap struct {
+	Left  float64
+	Right float64
+	wide  bool // шире columnGapRatio символов
+}
+
+func (g layoutGap) overlaps(other layoutGap) bool {
+	return g.Left < other.Right && other.Left < g.Right
+}
+
+func (g layoutGap) intersect(other layoutGap) layoutGap {
+	return layoutGap{Left: math.Max(g.Left, other.Left), Right: math.Min(g.Right, other.Right)}
+}
+
+// layoutBlock - таблица из нескольких строк или одиночная строка текста
+type layoutBlock struct {
+	rows  [][]string // ячейки таблицы
+	line  OCRLine    // строка текста, если это не таблица
+	table bool
+}
+
+// Layout восстанавливает разметку экрана: строки с выровненными колонками
+// (таблица подов, легенда графика) выводятся markdown таблицей, остальные - обычным текстом,
+// в котором отступы и промежутки между словами сохраняются пробелами.
+func (r OCRResult) Layout() string {
+	charWidth := r.charWidth()
+	left := r.left()
+
+	var texts []string
+	for _, block := range r.layoutBlocks(charWidth) {
+		if block.table {
+			texts = append(texts, renderTable(block.rows))
+			continue
+		}
+		texts = append(texts, renderLine(block.line, left, charWidth))
+	}
+	return strings.Join(texts, "\n")
+}
+
+// Tables возвращает число таблиц, найденных при реконструкции разметки
+func (r OCRResult) Tables() int {
+	tables := 0
+	for _, block := range r.layoutBlocks(r.charWidth()) {
+		if block.table {
+			tables++
+		}
+	}
+	return tables
+}
+
+// charWidth оценивает ширину символа как медиану по словам; 0, если координат нет
+func (r OCRResult) charWidth() float64 {
+	var widths []float64
+	for _, line := range r.Lines {
+		for _, word := range line.Words {
+			if runes := len([]rune(word.Text)); runes > 0 && !word.Box.Empty() {
+				widths = append(widths, float64(word.Box.Width())/float64(runes))
+			}
+		}
+	}
+	if len(widths) == 0 {
+		return 0
+	}
+	sort.Float64s(widths)
+	return widths[len(widths)/2]
+}
+
+// left возвращает левую границу текста - от нее отсчитываются отступы строк
+func (r OCRResult) left() int {
+	left := math.MaxInt
+	for _, line := range r.Lines {
+		for _, word := range line.Words {
+			if !word.Box.Empty() {
+				left = min(left, word.Box.Left)
+			}
+		}
+	}
+	return left
+}
+
+func (r OCRResult) layoutBlocks(charWidth float64) []layoutBlock {
+	var blocks []layoutBlock
+	for start := 0; start < len(r.Lines); {
+		end, separators := tableEnd(r.Lines, start, charWidth)
+		if end-start >= minTableRows {
+			blocks = append(blocks, layoutBlock{rows: splitColumns(r.Lines[start:end], separators), table: true})
+			start = end
+			continue
+		}
+		blocks = append(blocks, layoutBlock{line: r.Lines[start]})
+		start++
+	}
+	return blocks
+}
+
+// lineGaps возвращает промежутки строки, включая открытые полосы слева и справа от текста.
+// Без координат у строки нет промежутков.
+func lineGaps(line OCRLine, charWidth float64) []layoutGap {
+	if len(line.Words) == 0 || charWidth <= 0 {
+		return nil
+	}
+	for _, word := range line.Words {
+		if word.Box.Empty() {
+			return nil
+		}
+	}
+
+	gaps := []layoutGap{{Left: math.Inf(-1), Right: float64(line.Words[0].Box.Left)}}
+	for i := 1; i < len(line.Words); i++ {
+		gap := layoutGap{Left: float64(line.Words[i-1].Box.Right), Right: float64(line.Words[i].Box.Left)}
+		gap.wide = gap.Right-gap.Left > charWidth*columnGapRatio
+		gaps = append(gaps, gap)
+	}
+	return append(gaps, layoutGap{Left: float64(line.Words[len(line.Words)-1].Box.Right), Right: math.Inf(1)})
+}
+
+// tableEnd возвращает индекс, на котором заканчивается таблица, начатая строкой start,
+// и границы ее колонок. Граница колонки - полоса, свободная от слов во всех строках таблицы
+// и широкая хотя бы в одной из них: так колонки df, где в заголовке "Used Avail" разделены
+// одним пробелом, а в данных - несколькими, тоже разделяются. Строка продолжает таблицу,
+// если в ней есть широкий промежуток и ни одно ее слово не пересекает границу колонки.
+func tableEnd(lines []OCRLine, start int, charWidth float64) (int, []layoutGap) {
+	rows := [][]layoutGap{lineGaps(lines[start], charWidth)}
+	var separators []layoutGap
+	for _, gap := range rows[0] {
+		if gap.wide {
+			separators = append(separators, gap)
+		}
+	}
+	if len(separators) == 0 {
+		return start + 1, nil
+	}
+
+	end := start + 1
+	for ; end < len(lines); end++ {
+		gaps := lineGaps(lines[end], charWidth)
+		next, ok := extendSeparators(separators, rows, gaps)
+		if !ok {
+			break
+		}
+		separators = next
+		rows = append(rows, gaps)
+	}
+	return end, separators
+}
+
+// extendSeparators добавляет к таблице строку с промежутками gaps. Строка не подходит,
+// если в ней нет широкого промежутка или какое-то слово пересекает границу колонки.
+func extendSeparators(separators []layoutGap, rows [][]layoutGap, gaps []layoutGap) ([]layoutGap, bool) {
+	wide := false
+	for _, gap := range gaps {
+		wide = wide || gap.wide
+	}
+	if !wide {
+		return nil, false
+	}
+
+	next := make([]layoutGap, 0, len(separators))
+	for _, separator := range separators {
+		gap, ok := overlapping(gaps, separator)
+		if !ok {
+			return nil, false
+		}
+		next = append(next, separator.intersect(gap))
+	}
+
+	// Широкий промежуток новой строки становится границей, если он свободен и в прежних строках
+	for _, gap := range gaps {
+		if !gap.wide {
+			continue
+		}
+		if _, taken := overlapping(next, gap); taken {
+			continue
+		}
+		separator, free := gap, true
+		for _, row := range rows {
+			other, ok := overlapping(row, separator)
+			if !ok {
+				free = false
+				break
+			}
+			separator = separator.intersect(other)
+		}
+		if free {
+			next = append(next, separator)
+		}
+	}
+
+	sort.Slice(next, func(i, j int) bool { return next[i].Left < next[j].Left })
+	return next, true
+}
+
+// overlapping возвращает промежуток из gaps, пересекающийся с target
+func overlapping(gaps []layoutGap, target layoutGap) (layoutGap, bool) {
+	for _, gap := range gaps {
+		if gap.overlaps(target) {
+			return gap, true
+		}
+	}
+	return layoutGap{}, false
+}
+
+// splitColumns раскладывает слова строк по колонкам между границами separators
+func splitColumns(lines []OCRLine, separators []layoutGap) [][]string {
+	rows := make([][]string, len(lines))
+	for i, line := range lines {
+		cells := make([][]string, len(separators)+1)
+		for _, word := range line.Words {
+			column := sort.Search(len(separators), func(j int) bool {
+				return float64(word.Box.Left) < separators[j].Left
+			})
+			cells[column] = append(cells[column], word.Text)
+		}
+
+		rows[i] = make([]string, len(cells))
+		for j, words := range cells {
+			rows[i][j] = strings.Join(words, " ")
+		}
+	}
+	return rows
+}
+
+// renderLine выводит строку текста, расставляя слова по их позиции: отступ от левой
+// границы текста и промежутки переводятся в пробелы (число, выровненное по правому краю,
+// сохраняет отступ). Колонки разделяются хотя бы двумя пробелами.
+func renderLine(line OCRLine, left int, charWidth float64) string {
+	gaps := lineGaps(line, charWidth)
+	if gaps == nil {
+		return line.Text()
+	}
+
+	var b strings.Builder
+	column := 0
+	for i, word := range line.Words {
+		spaces := int(math.Round(float64(word.Box.Left-left)/charWidth)) - column
+		switch {
+		case i == 0:
+			spaces = max(spaces, 0)
+		case gaps[i].wide:
+			spaces = max(spaces, 2)
+		default:
+			spaces = max(spaces, 1)
+		}
+		b.WriteString(strings.Repeat(" ", spaces))
+		b.WriteString(word.Text)
+		column += spaces + len([]rune(word.Text))
+	}
+	return b.String()
+}
+
+// renderTable выводит строки markdown таблицей, первая строка считается заголовком
+func renderTable(rows [][]string) string {
+	var b strings.Builder
+	for i, row := range rows {
+		b.WriteString("|")
+		for _, cell := range row {
+			b.WriteString(" ")
+			b.WriteString(strings.ReplaceAll(cell, "|", `\|`))
+			b.WriteString(" |")
+		}
+		if i == 0 {
+			b.WriteString("\n|")
+			b.WriteString(strings.Repeat(" --- |", len(row)))
+		}
+		if i < len(rows)-1 {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,148 @@
+package vision
+
+import "testing"
+
+func TestLayoutTables(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		want   string
+		tables int
+	}{
+		{
+			name: "kubectl get pods",
+			text: `NAME                        READY   STATUS             RESTARTS      AGE
+payments-7d9f8c6b5-x2k4q    0/1     CrashLoopBackOff   5 (2m ago)    10m
+redis-0                     1/1     Running            0             3d`,
+			want: `| NAME | READY | STATUS | RESTARTS | AGE |
+| --- | --- | --- | --- | --- |
+| payments-7d9f8c6b5-x2k4q | 0/1 | CrashLoopBackOff | 5 (2m ago) | 10m |
+| redis-0 | 1/1 | Running | 0 | 3d |`,
+			tables: 1,
+		},
+		{
+			// Колонки через два пробела ("PR  NI") разделяются; через один ("PID USER") - нет
+			name: "top",
+			text: `    PID USER      PR  NI    VIRT    RES    SHR S  %CPU  %MEM     TIME+ COMMAND
+   1234 root      20   0  123456  45678   9012 S   1.3   0.5   0:01.23 nginx
+      1 root      20   0  167800  11240   8300 S   0.0   0.1   0:03.10 systemd`,
+			want: `| PID USER | PR | NI | VIRT | RES | SHR S | %CPU | %MEM | TIME+ COMMAND |
+| --- | --- | --- | --- | --- | --- | --- | --- | --- |
+| 1234 root | 20 | 0 | 123456 | 45678 | 9012 S | 1.3 | 0.5 | 0:01.23 nginx |
+| 1 root | 20 | 0 | 167800 | 11240 | 8300 S | 0.0 | 0.1 | 0:03.10 systemd |`,
+			tables: 1,
+		},
+		{
+			// В заголовке "Used Avail" через один пробел, но в данных колонки широкие
+			name: "df -h",
+			text: `Filesystem      Size  Used Avail Use% Mounted on
+/dev/sda1        50G   20G   28G  42% /
+tmpfs           7.8G     0  7.8G   0% /dev/shm`,
+			want: `| Filesystem | Size | Used | Avail | Use% Mounted on |
+| --- | --- | --- | --- | --- |
+| /dev/sda1 | 50G | 20G | 28G | 42% / |
+| tmpfs | 7.8G | 0 | 7.8G | 0% /dev/shm |`,
+			tables: 1,
+		},
+		{
+			name: "empty cells and pipes",
+			text: `KEY     VALUE     NOTE
+a|b     1
+c       2         x | y`,
+			want: `| KEY | VALUE | NOTE |
+| --- | --- | --- |
+| a\|b | 1 |  |
+| c | 2 | x \| y |`,
+			tables: 1,
+		},
+		{
+			name: "table between text",
+			text: `$ kubectl get svc
+NAME       TYPE        PORT(S)
+payments   ClusterIP   8080/TCP
+Error from server: the request timed out and the client gave up`,
+			want: `$ kubectl get svc
+| NAME | TYPE | PORT(S) |
+| --- | --- | --- |
+| payments | ClusterIP | 8080/TCP |
+Error from server: the request timed out and the client gave up`,
+			tables: 1,
+		},
+		{
+			name: "misaligned rows",
+			text: `load average:  0.52
+Tasks: 112 total,   1 running`,
+			want: `load average:  0.52
+Tasks: 112 total,   1 running`,
+		},
+		{
+			// Строки вне таблицы сохраняют отступ и промежутки
+			name: "indentation",
+			text: `top - 10:00:01 up 3 days
+      1 root
+Tasks:  12 total`,
+			want: `top - 10:00:01 up 3 days
+      1 root
+Tasks:  12 total`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := textResult(tt.text, 0.9)
+			if got := result.Layout(); got != tt.want {
+				t.Errorf("Layout:\n%s\nwant:\n%s", got, tt.want)
+			}
+			if got := result.Tables(); got != tt.tables {
+				t.Errorf("Tables = %d, want %d", got, tt.tables)
+			}
+		})
+	}
+}
+
+func TestLayoutColumnGap(t *testing.T) {
+	// Два пробела - граница колонки, один - промежуток между словами
+	for _, tt := range []struct {
+		text string
+		want string
+	}{
+		{text: "PR  NI\n20   0", want: "| PR | NI |\n| --- | --- |\n| 20 | 0 |"},
+		{text: "PR NI\n20 0", want: "PR NI\n20 0"},
+	} {
+		if got := textResult(tt.text, 1).Layout(); got != tt.want {
+			t.Errorf("Layout(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestLayoutWithoutBoxes(t *testing.T) {
+	// Движок без координат: строки выводятся как есть
+	result := OCRResult{Lines: []OCRLine{
+		{Words: []OCRWord{{Text: "NAME"}, {Text: "READY"}}},
+		{Words: []OCRWord{{Text: "redis-0"}, {Text: "1/1"}}},
+	}}
+	if got := result.Layout(); got != "NAME READY\nredis-0 1/1" {
+		t.Errorf("Layout = %q", got)
+	}
+	if result.Tables() != 0 {
+		t.Errorf("Tables = %d, want 0", result.Tables())
+	}
+}
+
+func TestLayoutProportionalFont(t *testing.T) {
+	// Легенда Grafana: слова разной ширины, колонки разделены широким промежутком
+	words := []OCRWord{
+		{Text: "Name", Box: BoundingBox{Left: 10, Top: 0, Right: 50, Bottom: 14}},
+		{Text: "Mean", Box: BoundingBox{Left: 200, Top: 0, Right: 240, Bottom: 14}},
+		{Text: "Max", Box: BoundingBox{Left: 300, Top: 0, Right: 330, Bottom: 14}},
+		{Text: "api", Box: BoundingBox{Left: 10, Top: 20, Right: 35, Bottom: 34}},
+		{Text: "latency", Box: BoundingBox{Left: 40, Top: 20, Right: 100, Bottom: 34}},
+		{Text: "120ms", Box: BoundingBox{Left: 195, Top: 20, Right: 245, Bottom: 34}},
+		{Text: "2.1s", Box: BoundingBox{Left: 300, Top: 20, Right: 335, Bottom: 34}},
+	}
+	got := newOCRResult(words).Layout()
+	want := "| Name | Mean | Max |\n| --- | --- | --- |\n| api latency | 120ms | 2.1s |"
+	if got != want {
+		t.Errorf("Layout:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -20,7 +20,7 @@ func (m *MockOCR) Initialize() error {
 	return nil
 }
 
-func (m *MockOCR) ExtractText(ctx context.Context, imageData []byte) (string, error) {
+func (m *MockOCR) Recognize(ctx context.Context, imageData []byte) (OCRResult, error) {
 	m.counter++
 
 	// Симулируем различные типы логов и метрик
@@ -29,6 +29,7 @@ func (m *MockOCR) ExtractText(ctx context.Context, imageData []byte) (string, er
 		"CPU: 95%\nMemory: 8.2GB/16GB\nDisk I/O: 85%\nNetwork: 450Mbps",
 		"2025-10-16 14:23:45 [ERROR] Failed to connect to service\n2025-10-16 14:23:46 [WARN] Retrying...\n2025-10-16 14:23:47 [INFO] Connection restored",
 		"Pod Status: CrashLoopBackOff\nRestarts: 5\nLast Error: OutOfMemory",
+		"NAME                       READY   STATUS             RESTARTS\napi-gateway-7d9f8b-x2k4p   0/1     CrashLoopBackOff   5\npayments-5c6d7f-9qwrt      1/1     Running            0\npostgres-0                 1/1     Running            2",
 		"HTTP/1.1 503 Service Unavailable\nRetry-After: 60\nContent-Length: 1234",
 	}
 
@@ -38,9 +39,9 @@ func (m *MockOCR) ExtractText(ctx context.Context, imageData []byte) (string, er
 	// Симулируем задержку обработки
 	select {
 	case <-time.After(800 * time.Millisecond):
-		return ocrText, nil
+		return textResult(ocrText, 1), nil
 	case <-ctx.Done():
-		return "", ctx.Err()
+		return OCRResult{}, ctx.Err()
 	}
 }
 
@@ -1,8 +1,15 @@
 package vision
 
 import (
+	"bytes"
 	"context"
+	"image"
+	"image/draw"
+	"image/png"
 	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"cluely/internal/config"
@@ -12,15 +19,27 @@ import (
 type Module struct {
 	cfg         config.VisionConfig
 	ocrEngine   OCREngine
-	screenshots chan []byte
+	source      ScreenshotSource
+	windows     WindowWatcher
+	dedup       *frameDeduper
+	screenshots chan Screenshot
 	stopCh      chan struct{}
+	mu          sync.Mutex // защищает isRunning, focusApp и отправку в screenshots от закрытия канала
 	isRunning   bool
+	focusApp    string // отслеживаемое приложение в фокусе по данным windows
+	paused      atomic.Bool
+
+	// Последний результат OCR по каждому источнику - основа для распознавания только изменений
+	resultsMu sync.Mutex
+	results   map[string]OCRResult
 }
 
 func NewModule(cfg config.VisionConfig) *Module {
 	return &Module{
 		cfg:         cfg,
-		screenshots: make(chan []byte, 10),
+		dedup:       newFrameDeduper(cfg.DedupDistance, cfg.ChangeGrid),
+		results:     make(map[string]OCRResult),
+		screenshots: make(chan Screenshot, 10),
 		stopCh:      make(chan struct{}),
 	}
 }
@@ -41,16 +60,110 @@ func (m *Module) Start(ctx context.Context) error {
 		return err
 	}
 
+	if m.cfg.Source != "" && m.cfg.Source != "mock" {
+		pollInterval := time.Duration(m.cfg.PollIntervalMs) * time.Millisecond
+		source, err := NewScreenshotSource(m.cfg.Source, m.cfg.SourcePath, m.cfg.SourceApp, pollInterval)
+		if err != nil {
+			ocrEngine.Close()
+			return err
+		}
+		m.source = source
+	}
+
+	if m.cfg.WindowWatcher != "" && m.cfg.WindowWatcher != "none" {
+		windows, err := NewWindowWatcher(m.cfg.WindowWatcher, m.cfg.WindowFile)
+		if err == nil {
+			err = windows.Initialize()
+		}
+		if err != nil {
+			if m.source != nil {
+				m.source.Close()
+			}
+			ocrEngine.Close()
+			return err
+		}
+		m.windows = windows
+	}
+
 	m.ocrEngine = ocrEngine
+	m.mu.Lock()
 	m.isRunning = true
+	m.mu.Unlock()
 
-	// Запускаем горутину для симуляции захвата скриншотов
-	go m.simulateScreenshotCapture(ctx)
+	if m.source != nil {
+		go m.runSource(ctx)
+	} else {
+		// Запускаем горутину для симуляции захвата скриншотов
+		go m.simulateScreenshotCapture(ctx)
+	}
+	if m.windows != nil {
+		go m.watchFocus(ctx)
+	}
 
-	log.Printf("✅ Vision Module started (OCR engine: %s)", m.cfg.OCREngine)
+	log.Printf("✅ Vision Module started (OCR engine: %s, source: %s)", m.cfg.OCREngine, m.cfg.Source)
 	return nil
 }
 
+func (m *Module) runSource(ctx context.Context) {
+	if err := m.source.Run(ctx, m.publish); err != nil {
+		log.Printf("❌ Screenshot source failed: %v", err)
+	}
+}
+
+// publish отбрасывает повторы предыдущего кадра и ставит скриншот в очередь без блокировки;
+// при переполненной очереди кадр отбрасывается
+func (m *Module) publish(shot Screenshot) bool {
+	// На паузе кадры источника отбрасываются, но считаются обработанными
+	if m.paused.Load() {
+		return true
+	}
+	if shot.App == "" {
+		shot.App = m.FocusedApp()
+	}
+
+	// Mock кадры синтетические и одинаковые - дедупликация оставила бы только первый
+	var sig frameSignature
+	dedup := shot.Source != "mock"
+	if dedup {
+		img, _, err := image.Decode(bytes.NewReader(shot.Data))
+		if err != nil {
+			log.Printf("⚠️  Screenshot decode failed, dedup skipped: %v", err)
+			dedup = false
+		} else {
+			var duplicate bool
+			var distance int
+			sig, duplicate, distance, shot.Changed = m.dedup.Check(shot.key(), img)
+			switch {
+			case duplicate && shot.Trigger != "":
+				// Снимок запрошен явно - анализируем его, переиспользуя прошлый OCR целиком
+				shot.Changed = []image.Rectangle{}
+			case duplicate:
+				log.Printf("🔁 Screenshot skipped: same as previous frame (distance %d, %s, app %q)", distance, shot.Source, shot.App)
+				return true
+			}
+			shot.image = img
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.isRunning {
+		return false
+	}
+
+	select {
+	case m.screenshots <- shot:
+		if dedup {
+			m.dedup.Remember(shot.key(), sig)
+		}
+		return true
+	default:
+		log.Printf("⚠️  Screenshot dropped: queue is full (%s, app %q)", shot.Source, shot.App)
+		return false
+	}
+}
+
 func (m *Module) simulateScreenshotCapture(ctx context.Context) {
 	// В mock режиме отправляем скриншоты каждые 10 секунд
 	ticker := time.NewTicker(10 * time.Second)
@@ -63,37 +176,247 @@ func (m *Module) simulateScreenshotCapture(ctx context.Context) {
 		case <-m.stopCh:
 			return
 		case <-ticker.C:
-			// Симулируем захват скриншота (в реальной версии это был бы bytes от скриншота)
-			dummyScreenshot := []byte("mock_screenshot_data")
-			select {
-			case m.screenshots <- dummyScreenshot:
+			if m.publish(m.mockScreenshot(m.cfg.SourceApp)) {
 				log.Println("📸 Mock screenshot captured")
-			case <-ctx.Done():
-				return
 			}
 		}
 	}
 }
 
-func (m *Module) ExtractText(ctx context.Context, imageData []byte) (string, error) {
+func (m *Module) mockScreenshot(app string) Screenshot {
+	return Screenshot{
+		Data:       mockFrame(),
+		Format:     "png",
+		Width:      mockFrameWidth,
+		Height:     mockFrameHeight,
+		CapturedAt: time.Now(),
+		App:        app,
+		Source:     "mock",
+	}
+}
+
+const (
+	mockFrameWidth  = 320
+	mockFrameHeight = 180
+)
+
+// mockFrame возвращает пустой PNG кадр, чтобы mock скриншоты были валидными изображениями
+func mockFrame() []byte {
+	img := image.NewGray(image.Rect(0, 0, mockFrameWidth, mockFrameHeight))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+// Recognize распознает скриншот и возвращает строки со словами, координатами и уверенностью.
+// Если известны изменившиеся области, распознаются только они, а остальной текст берется
+// из предыдущего результата того же источника.
+// Для промптов используйте OCRResult.Layout - он сохраняет колонки и таблицы.
+func (m *Module) Recognize(ctx context.Context, shot Screenshot) (OCRResult, error) {
 	if m.ocrEngine == nil {
-		return "", nil
+		return OCRResult{}, nil
+	}
+
+	key := shot.key()
+	m.resultsMu.Lock()
+	prev, cached := m.results[key]
+	m.resultsMu.Unlock()
+
+	if !cached || shot.Changed == nil || shot.image == nil {
+		result, err := m.ocrEngine.Recognize(ctx, shot.Data)
+		m.rememberResult(key, result, err)
+		return result, err
+	}
+
+	result, err := m.recognizeRegions(ctx, shot, prev)
+	m.rememberResult(key, result, err)
+	return result, err
+}
+
+// recognizeRegions распознает только изменившиеся области и подставляет их слова
+// вместо слов предыдущего результата, попавших в эти области
+func (m *Module) recognizeRegions(ctx context.Context, shot Screenshot, prev OCRResult) (OCRResult, error) {
+	var prevWords []OCRWord
+	for _, line := range prev.Lines {
+		prevWords = append(prevWords, line.Words...)
+	}
+
+	bounds := shot.image.Bounds()
+	regions := expandRegions(shot.Changed, prevWords, bounds)
+
+	area := 0
+	for _, region := range regions {
+		area += region.Dx() * region.Dy()
+	}
+	frameArea := max(bounds.Dx()*bounds.Dy(), 1)
+	// После расширения области могут покрыть почти весь кадр - тогда проще распознать его целиком
+	if float64(area) > float64(frameArea)*fullFrameRatio {
+		return m.ocrEngine.Recognize(ctx, shot.Data)
+	}
+
+	var words []OCRWord
+	for _, word := range prevWords {
+		if !overlapsAny(word.Box, regions) {
+			words = append(words, word)
+		}
 	}
-	return m.ocrEngine.ExtractText(ctx, imageData)
+
+	for _, region := range regions {
+		crop, err := cropPNG(shot.image, region)
+		if err != nil {
+			return OCRResult{}, err
+		}
+		result, err := m.ocrEngine.Recognize(ctx, crop)
+		if err != nil {
+			return OCRResult{}, err
+		}
+
+		for _, line := range result.Lines {
+			for _, word := range line.Words {
+				word.Box = word.Box.offset(region.Min)
+				words = append(words, word)
+			}
+		}
+	}
+
+	log.Printf("🧩 OCR of %d changed region(s), %d%% of the frame", len(regions), area*100/frameArea)
+	return newOCRResult(words), nil
+}
+
+// regionMargin - запас вокруг изменившейся области, чтобы новое слово на ее границе
+// не оказалось обрезанным в вырезке
+const regionMargin = 4
+
+// expandRegions расширяет изменившиеся области так, чтобы каждое задетое ими слово прошлого
+// результата целиком попадало внутрь (с запасом regionMargin), и объединяет пересекшиеся
+// области. Иначе слово, выброшенное из прошлого результата, в вырезке оказалось бы обрезанным,
+// а строка, разделенная между двумя областями, распозналась бы обрывками.
+func expandRegions(changed []image.Rectangle, words []OCRWord, bounds image.Rectangle) []image.Rectangle {
+	regions := make([]image.Rectangle, 0, len(changed))
+	for _, region := range changed {
+		regions = append(regions, region.Inset(-regionMargin).Intersect(bounds))
+	}
+
+	for grown := true; grown; {
+		grown = false
+
+		for i := range regions {
+			for _, word := range words {
+				if word.Box.Empty() {
+					continue
+				}
+				rect := image.Rect(word.Box.Left, word.Box.Top, word.Box.Right, word.Box.Bottom).Intersect(bounds)
+				if rect.Empty() || !rect.Overlaps(regions[i]) || rect.In(regions[i]) {
+					continue
+				}
+				regions[i] = regions[i].Union(rect.Inset(-regionMargin)).Intersect(bounds)
+				grown = true
+			}
+		}
+
+		// Пересекшиеся области распознаются одной вырезкой, чтобы слова не дублировались
+		for i := 0; i < len(regions); i++ {
+			for j := i + 1; j < len(regions); j++ {
+				if regions[i].Overlaps(regions[j]) {
+					regions[i] = regions[i].Union(regions[j])
+					regions = append(regions[:j], regions[j+1:]...)
+					grown = true
+					j = i
+				}
+			}
+		}
+	}
+
+	return regions
+}
+
+// rememberResult сохраняет результат для следующего частичного распознавания.
+// После ошибки кэш сбрасывается: следующие изменения считаются от кадра, которого нет в кэше.
+func (m *Module) rememberResult(key string, result OCRResult, err error) {
+	m.resultsMu.Lock()
+	defer m.resultsMu.Unlock()
+
+	if err != nil {
+		delete(m.results, key)
+		return
+	}
+	m.results[key] = result
 }
 
-func (m *Module) ScreenshotChannel() <-chan []byte {
+func overlapsAny(box BoundingBox, regions []image.Rectangle) bool {
+	rect := image.Rect(box.Left, box.Top, box.Right, box.Bottom)
+	for _, region := range regions {
+		if rect.Overlaps(region) {
+			return true
+		}
+	}
+	return false
+}
+
+// cropPNG вырезает область кадра и кодирует ее в PNG для OCR движка
+func cropPNG(img image.Image, region image.Rectangle) ([]byte, error) {
+	crop := image.NewRGBA(image.Rect(0, 0, region.Dx(), region.Dy()))
+	draw.Draw(crop, crop.Bounds(), img, region.Min, draw.Src)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, crop); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (m *Module) ScreenshotChannel() <-chan Screenshot {
 	return m.screenshots
 }
 
+// SetPaused приостанавливает или возобновляет захват экрана без остановки модуля
+func (m *Module) SetPaused(paused bool) {
+	if m.paused.Swap(paused) == paused {
+		return
+	}
+	if paused {
+		log.Println("⏸️  Screen capture paused")
+	} else {
+		log.Println("▶️  Screen capture resumed")
+	}
+}
+
+// Paused сообщает, приостановлен ли захват экрана
+func (m *Module) Paused() bool {
+	return m.paused.Load()
+}
+
+// UploadHandler возвращает HTTP обработчик загрузки скриншотов, если vision.source = "upload"
+func (m *Module) UploadHandler() http.Handler {
+	if upload, ok := m.source.(*UploadSource); ok {
+		return upload
+	}
+	return nil
+}
+
 func (m *Module) Stop() {
+	m.mu.Lock()
 	if !m.isRunning {
+		m.mu.Unlock()
 		return
 	}
-
 	m.isRunning = false
+	m.mu.Unlock()
+
 	close(m.stopCh)
 
+	if m.source != nil {
+		m.source.Close()
+	}
+
+	if m.windows != nil {
+		m.windows.Close()
+	}
+
 	if m.ocrEngine != nil {
 		m.ocrEngine.Close()
 	}
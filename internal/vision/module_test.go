@@ -0,0 +1,189 @@
+package vision
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"reflect"
+	"testing"
+
+	"cluely/internal/config"
+)
+
+// cropOCR - OCR движок для тестов: запоминает размеры вырезок и возвращает заданные слова
+// в координатах вырезки
+type cropOCR struct {
+	crops []image.Point
+	words func(size image.Point) []OCRWord
+}
+
+func (c *cropOCR) Initialize() error { return nil }
+func (c *cropOCR) Close() error      { return nil }
+
+func (c *cropOCR) Recognize(ctx context.Context, data []byte) (OCRResult, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return OCRResult{}, err
+	}
+	size := img.Bounds().Size()
+	c.crops = append(c.crops, size)
+	return newOCRResult(c.words(size)), nil
+}
+
+func word(text string, left, top, right, bottom int) OCRWord {
+	return OCRWord{Text: text, Box: BoundingBox{Left: left, Top: top, Right: right, Bottom: bottom}, Confidence: 1}
+}
+
+func ocrWords(result OCRResult) []string {
+	var texts []string
+	for _, line := range result.Lines {
+		for _, w := range line.Words {
+			texts = append(texts, w.Text)
+		}
+	}
+	return texts
+}
+
+func testFrame(t *testing.T, width, height int) Screenshot {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return Screenshot{Data: buf.Bytes(), Source: "dir", image: img}
+}
+
+func TestExpandRegions(t *testing.T) {
+	bounds := image.Rect(0, 0, 400, 200)
+	tests := []struct {
+		name    string
+		changed []image.Rectangle
+		words   []OCRWord
+		want    []image.Rectangle
+	}{
+		{
+			name:    "margin only",
+			changed: []image.Rectangle{image.Rect(100, 100, 200, 150)},
+			words:   []OCRWord{word("far", 300, 10, 350, 30)},
+			want:    []image.Rectangle{image.Rect(96, 96, 204, 154)},
+		},
+		{
+			name:    "word on the border",
+			changed: []image.Rectangle{image.Rect(100, 0, 200, 50)},
+			words:   []OCRWord{word("payments-7d9f", 60, 10, 140, 30)},
+			want:    []image.Rectangle{image.Rect(56, 0, 204, 54)},
+		},
+		{
+			name:    "clamped to frame",
+			changed: []image.Rectangle{image.Rect(0, 0, 50, 50)},
+			words:   []OCRWord{word("edge", 0, 0, 60, 20)},
+			want:    []image.Rectangle{image.Rect(0, 0, 64, 54)},
+		},
+		{
+			name: "word across two regions",
+			changed: []image.Rectangle{
+				image.Rect(0, 0, 100, 50),
+				image.Rect(200, 0, 300, 50),
+			},
+			words: []OCRWord{word("CrashLoopBackOff", 80, 10, 220, 30)},
+			want:  []image.Rectangle{image.Rect(0, 0, 304, 54)},
+		},
+		{
+			// Слова целиком в своих областях; строка соберется из них по координатам
+			name: "line across two regions",
+			changed: []image.Rectangle{
+				image.Rect(0, 0, 100, 50),
+				image.Rect(200, 0, 300, 50),
+			},
+			words: []OCRWord{
+				word("CrashLoopBackOff", 80, 10, 160, 30),
+				word("restarts", 180, 10, 220, 30),
+			},
+			want: []image.Rectangle{image.Rect(0, 0, 164, 54), image.Rect(176, 0, 304, 54)},
+		},
+		{
+			name: "chain of words",
+			changed: []image.Rectangle{
+				image.Rect(0, 100, 40, 140),
+			},
+			words: []OCRWord{
+				word("a", 30, 110, 60, 130),
+				word("b", 62, 110, 90, 130),
+				word("c", 200, 110, 230, 130),
+			},
+			want: []image.Rectangle{image.Rect(0, 96, 94, 144)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := expandRegions(tt.changed, tt.words, bounds)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandRegions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecognizeRegionsReplacesStraddlingWords(t *testing.T) {
+	engine := &cropOCR{words: func(size image.Point) []OCRWord {
+		// Вырезка начинается в (56, 0): слово занимает ту же позицию, что и прежнее
+		return []OCRWord{word("payments-8c1a", 4, 10, 84, 30)}
+	}}
+	m := NewModule(configForTest())
+	m.ocrEngine = engine
+
+	prev := newOCRResult([]OCRWord{
+		word("STATUS", 300, 10, 360, 30),
+		word("payments-7d9f", 60, 10, 140, 30),
+		word("Running", 300, 60, 360, 80),
+	})
+	shot := testFrame(t, 400, 200)
+	shot.Changed = []image.Rectangle{image.Rect(100, 0, 200, 50)}
+
+	result, err := m.recognizeRegions(context.Background(), shot, prev)
+	if err != nil {
+		t.Fatalf("recognizeRegions: %v", err)
+	}
+
+	if want := []image.Point{{148, 54}}; !reflect.DeepEqual(engine.crops, want) {
+		t.Errorf("crops = %v, want %v", engine.crops, want)
+	}
+	want := []string{"payments-8c1a", "STATUS", "Running"}
+	if got := ocrWords(result); !reflect.DeepEqual(got, want) {
+		t.Errorf("words = %q, want %q", got, want)
+	}
+	if box := result.Lines[0].Words[0].Box; box != (BoundingBox{Left: 60, Top: 10, Right: 140, Bottom: 30}) {
+		t.Errorf("new word box = %+v, want it in frame coordinates", box)
+	}
+}
+
+func TestRecognizeRegionsFallsBackToFullFrame(t *testing.T) {
+	engine := &cropOCR{words: func(size image.Point) []OCRWord {
+		return []OCRWord{word("full", 0, 0, 40, 20)}
+	}}
+	m := NewModule(configForTest())
+	m.ocrEngine = engine
+
+	// Строка во всю ширину кадра растягивает небольшую область на большую часть кадра
+	prev := newOCRResult([]OCRWord{word("long-line", 0, 0, 400, 130)})
+	shot := testFrame(t, 400, 200)
+	shot.Changed = []image.Rectangle{image.Rect(180, 20, 220, 60)}
+
+	result, err := m.recognizeRegions(context.Background(), shot, prev)
+	if err != nil {
+		t.Fatalf("recognizeRegions: %v", err)
+	}
+	if want := []image.Point{{400, 200}}; !reflect.DeepEqual(engine.crops, want) {
+		t.Errorf("crops = %v, want full frame %v", engine.crops, want)
+	}
+	if got := ocrWords(result); !reflect.DeepEqual(got, []string{"full"}) {
+		t.Errorf("words = %q", got)
+	}
+}
+
+func configForTest() config.VisionConfig {
+	return config.VisionConfig{Enabled: true, OCREngine: "mock"}
+}
@@ -6,7 +6,7 @@ import (
 )
 
 type OCREngine interface {
-	ExtractText(ctx context.Context, imageData []byte) (string, error)
+	Recognize(ctx context.Context, imageData []byte) (OCRResult, error)
 	Initialize() error
 	Close() error
 }
@@ -14,9 +14,9 @@ type OCREngine interface {
 func NewOCREngine(engineType string, config map[string]string) (OCREngine, error) {
 	switch engineType {
 	case "tesseract":
-		// TODO: Implement Tesseract OCR
-		// For now, fallback to mock
-		return NewMockOCR(), nil
+		return NewTesseractOCR(config), nil
+	case "paddle":
+		return NewPaddleOCR(config), nil
 	case "mock":
 		return NewMockOCR(), nil
 	default:
@@ -0,0 +1,196 @@
+package vision
+
+import (
+	"image"
+	"math"
+	"sort"
+	"strings"
+)
+
+// BoundingBox - выровненный по осям прямоугольник в пикселях изображения
+type BoundingBox struct {
+	Left   int
+	Top    int
+	Right  int
+	Bottom int
+}
+
+func (b BoundingBox) Width() int  { return b.Right - b.Left }
+func (b BoundingBox) Height() int { return b.Bottom - b.Top }
+
+// Empty сообщает, что координаты неизвестны (движок вернул только текст)
+func (b BoundingBox) Empty() bool {
+	return b.Right <= b.Left || b.Bottom <= b.Top
+}
+
+func (b BoundingBox) offset(origin image.Point) BoundingBox {
+	if b.Empty() {
+		return b
+	}
+	return BoundingBox{
+		Left:   b.Left + origin.X,
+		Top:    b.Top + origin.Y,
+		Right:  b.Right + origin.X,
+		Bottom: b.Bottom + origin.Y,
+	}
+}
+
+func (b BoundingBox) union(other BoundingBox) BoundingBox {
+	if b.Empty() {
+		return other
+	}
+	if other.Empty() {
+		return b
+	}
+	return BoundingBox{
+		Left:   min(b.Left, other.Left),
+		Top:    min(b.Top, other.Top),
+		Right:  max(b.Right, other.Right),
+		Bottom: max(b.Bottom, other.Bottom),
+	}
+}
+
+// OCRWord - распознанное слово (или фраза, если движок не делит текст на слова)
+type OCRWord struct {
+	Text       string
+	Box        BoundingBox
+	Confidence float64 // 0-1
+}
+
+// OCRLine - строка текста, слова упорядочены слева направо
+type OCRLine struct {
+	Words      []OCRWord
+	Box        BoundingBox
+	Confidence float64
+}
+
+// Text возвращает текст строки, слова разделены пробелом
+func (l OCRLine) Text() string {
+	texts := make([]string, len(l.Words))
+	for i, word := range l.Words {
+		texts[i] = word.Text
+	}
+	return strings.Join(texts, " ")
+}
+
+// OCRResult - структурированный результат OCR: строки в порядке чтения
+type OCRResult struct {
+	Lines      []OCRLine
+	Confidence float64 // средняя уверенность по словам
+}
+
+// Text возвращает плоский текст без реконструкции колонок
+func (r OCRResult) Text() string {
+	texts := make([]string, len(r.Lines))
+	for i, line := range r.Lines {
+		texts[i] = line.Text()
+	}
+	return strings.Join(texts, "\n")
+}
+
+// WordCount возвращает число распознанных слов
+func (r OCRResult) WordCount() int {
+	count := 0
+	for _, line := range r.Lines {
+		count += len(line.Words)
+	}
+	return count
+}
+
+// newOCRResult собирает слова в строки по геометрии: сверху вниз, слева направо.
+// Слово попадает в строку, если его вертикальный центр лежит внутри высоты строки -
+// так небольшой наклон или разная высота шрифта не разрывают строку.
+func newOCRResult(words []OCRWord) OCRResult {
+	sorted := make([]OCRWord, len(words))
+	copy(sorted, words)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Box.Top+sorted[i].Box.Bottom < sorted[j].Box.Top+sorted[j].Box.Bottom
+	})
+
+	var lines []OCRLine
+	for _, word := range sorted {
+		center := (word.Box.Top + word.Box.Bottom) / 2
+		if n := len(lines); n > 0 && center >= lines[n-1].Box.Top && center <= lines[n-1].Box.Bottom {
+			lines[n-1].Words = append(lines[n-1].Words, word)
+			lines[n-1].Box = lines[n-1].Box.union(word.Box)
+			continue
+		}
+		lines = append(lines, OCRLine{Words: []OCRWord{word}, Box: word.Box})
+	}
+
+	for i := range lines {
+		sort.SliceStable(lines[i].Words, func(a, b int) bool {
+			return lines[i].Words[a].Box.Left < lines[i].Words[b].Box.Left
+		})
+	}
+
+	return finishResult(lines)
+}
+
+// Геометрия моноширинного текста для textResult
+const (
+	textCharWidth  = 8
+	textGlyphSize  = 16
+	textLineHeight = 20
+)
+
+// textResult строит результат из готового текста без координат. Слова получают координаты
+// моноширинной сетки по позиции символов, поэтому выравнивание пробелами (вывод kubectl,
+// top) сохраняется и распознается как колонки.
+func textResult(text string, confidence float64) OCRResult {
+	var words []OCRWord
+	row := 0
+	for _, line := range strings.Split(text, "\n") {
+		runes := []rune(line)
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		for col := 0; col < len(runes); {
+			if runes[col] == ' ' || runes[col] == '\t' {
+				col++
+				continue
+			}
+			end := col
+			for end < len(runes) && runes[end] != ' ' && runes[end] != '\t' {
+				end++
+			}
+			words = append(words, OCRWord{
+				Text: string(runes[col:end]),
+				Box: BoundingBox{
+					Left:   col * textCharWidth,
+					Top:    row * textLineHeight,
+					Right:  end * textCharWidth,
+					Bottom: row*textLineHeight + textGlyphSize,
+				},
+				Confidence: confidence,
+			})
+			col = end
+		}
+		row++
+	}
+	return newOCRResult(words)
+}
+
+// finishResult считает уверенность строк и результата
+func finishResult(lines []OCRLine) OCRResult {
+	var total float64
+	var count int
+	for i := range lines {
+		var sum float64
+		for _, word := range lines[i].Words {
+			sum += word.Confidence
+		}
+		if len(lines[i].Words) > 0 {
+			lines[i].Confidence = sum / float64(len(lines[i].Words))
+		}
+		total += sum
+		count += len(lines[i].Words)
+	}
+
+	result := OCRResult{Lines: lines}
+	if count > 0 {
+		result.Confidence = math.Round(total/float64(count)*1000) / 1000
+	}
+	return result
+}
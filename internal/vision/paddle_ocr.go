@@ -0,0 +1,183 @@
+package vision
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PaddleOCR отправляет изображение на локальный сервис PaddleOCR (PaddleHub Serving)
+//
+// Параметры из ocr_config:
+//   - url: адрес predict эндпоинта, по умолчанию http://localhost:8866/predict/ch_pp-ocrv3
+//   - timeout_ms: таймаут запроса, по умолчанию 10000
+//   - min_confidence: блоки с уверенностью ниже порога (0-1) отбрасываются, по умолчанию 0
+//   - lang: язык распознавания, по умолчанию "en"
+//   - use_angle_cls: "false" отключает классификатор угла поворота текста, по умолчанию включен
+type PaddleOCR struct {
+	endpoint      string
+	minConfidence float64
+	lang          string
+	useAngleCls   bool
+	client        *http.Client
+}
+
+type paddleRequest struct {
+	Images      []string `json:"images"`
+	Lang        string   `json:"lang"`
+	UseAngleCls bool     `json:"use_angle_cls"`
+}
+
+type paddleResult struct {
+	Text       string      `json:"text"`
+	Confidence float64     `json:"confidence"`
+	Box        [][]float64 `json:"text_box_position"`
+}
+
+type paddleResponse struct {
+	Status  string `json:"status"`
+	Msg     string `json:"msg"`
+	Results []struct {
+		Data []paddleResult `json:"data"`
+	} `json:"results"`
+}
+
+func NewPaddleOCR(config map[string]string) *PaddleOCR {
+	endpoint := config["url"]
+	if endpoint == "" {
+		endpoint = "http://localhost:8866/predict/ch_pp-ocrv3"
+	}
+	timeout, err := strconv.Atoi(config["timeout_ms"])
+	if err != nil || timeout <= 0 {
+		timeout = 10000
+	}
+	minConfidence, err := strconv.ParseFloat(config["min_confidence"], 64)
+	if err != nil || minConfidence < 0 {
+		minConfidence = 0
+	}
+	lang := config["lang"]
+	if lang == "" {
+		lang = "en"
+	}
+
+	return &PaddleOCR{
+		endpoint:      endpoint,
+		minConfidence: minConfidence,
+		lang:          lang,
+		useAngleCls:   config["use_angle_cls"] != "false",
+		client: &http.Client{
+			Timeout: time.Duration(timeout) * time.Millisecond,
+		},
+	}
+}
+
+func (p *PaddleOCR) Initialize() error {
+	if _, err := url.ParseRequestURI(p.endpoint); err != nil {
+		return fmt.Errorf("invalid paddle ocr url: %w", err)
+	}
+
+	log.Printf("📸 PaddleOCR initialized (endpoint: %s, lang: %s, use_angle_cls: %t)", p.endpoint, p.lang, p.useAngleCls)
+	return nil
+}
+
+// Recognize отправляет изображение на сервис и собирает найденные текстовые блоки в строки
+// в порядке чтения. Блок PaddleOCR обычно содержит фразу, она становится одним словом результата.
+func (p *PaddleOCR) Recognize(ctx context.Context, imageData []byte) (OCRResult, error) {
+	if len(imageData) == 0 {
+		return OCRResult{}, nil
+	}
+
+	payload, err := json.Marshal(paddleRequest{
+		Images:      []string{base64.StdEncoding.EncodeToString(imageData)},
+		Lang:        p.lang,
+		UseAngleCls: p.useAngleCls,
+	})
+	if err != nil {
+		return OCRResult{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return OCRResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return OCRResult{}, fmt.Errorf("paddle ocr request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return OCRResult{}, fmt.Errorf("paddle ocr returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var paddleResp paddleResponse
+	if err := json.NewDecoder(resp.Body).Decode(&paddleResp); err != nil {
+		return OCRResult{}, fmt.Errorf("paddle ocr response decode failed: %w", err)
+	}
+	// PaddleHub Serving отвечает статусом "000" при успехе
+	if paddleResp.Status != "" && paddleResp.Status != "000" {
+		return OCRResult{}, fmt.Errorf("paddle ocr error (status %s): %s", paddleResp.Status, paddleResp.Msg)
+	}
+
+	var words []OCRWord
+	for _, result := range paddleResp.Results {
+		for _, item := range result.Data {
+			text := strings.TrimSpace(item.Text)
+			if text == "" || item.Confidence < p.minConfidence {
+				continue
+			}
+			box, ok := boundingBox(item.Box)
+			if !ok {
+				continue
+			}
+			words = append(words, OCRWord{Text: text, Box: box, Confidence: item.Confidence})
+		}
+	}
+
+	return newOCRResult(words), nil
+}
+
+// boundingBox переводит четырехугольник из точек [x, y] в выровненный по осям прямоугольник
+func boundingBox(points [][]float64) (BoundingBox, bool) {
+	if len(points) == 0 {
+		return BoundingBox{}, false
+	}
+
+	left, top := math.Inf(1), math.Inf(1)
+	right, bottom := math.Inf(-1), math.Inf(-1)
+	for _, point := range points {
+		if len(point) < 2 {
+			return BoundingBox{}, false
+		}
+		left = math.Min(left, point[0])
+		right = math.Max(right, point[0])
+		top = math.Min(top, point[1])
+		bottom = math.Max(bottom, point[1])
+	}
+
+	box := BoundingBox{
+		Left:   int(math.Floor(left)),
+		Top:    int(math.Floor(top)),
+		Right:  int(math.Ceil(right)),
+		Bottom: int(math.Ceil(bottom)),
+	}
+	return box, !box.Empty()
+}
+
+func (p *PaddleOCR) Close() error {
+	log.Println("📸 PaddleOCR closed")
+	return nil
+}
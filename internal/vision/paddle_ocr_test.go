@@ -0,0 +1,161 @@
+package vision
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// paddleStub - заглушка PaddleHub Serving, запоминающая последний запрос
+type paddleStub struct {
+	server   *httptest.Server
+	request  paddleRequest
+	status   int
+	response string
+}
+
+func newPaddleStub(t *testing.T, response string) *paddleStub {
+	t.Helper()
+	stub := &paddleStub{status: http.StatusOK, response: response}
+	stub.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/predict/ch_pp-ocrv3" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &stub.request); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+
+		w.WriteHeader(stub.status)
+		io.WriteString(w, stub.response)
+	}))
+	t.Cleanup(stub.server.Close)
+	return stub
+}
+
+func newTestPaddle(t *testing.T, stub *paddleStub, extra map[string]string) OCREngine {
+	t.Helper()
+	cfg := map[string]string{"url": stub.server.URL + "/predict/ch_pp-ocrv3"}
+	for key, value := range extra {
+		cfg[key] = value
+	}
+
+	engine, err := NewOCREngine("paddle", cfg)
+	if err != nil {
+		t.Fatalf("NewOCREngine: %v", err)
+	}
+	if err := engine.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	return engine
+}
+
+// Блоки перемешаны: вторая строка идет первой, правая колонка раньше левой
+const paddleUnorderedResponse = `{"status":"000","msg":"","results":[{"data":[
+	{"text":"Running","confidence":0.93,"text_box_position":[[210,52],[290,50],[291,70],[211,72]]},
+	{"text":"STATUS","confidence":0.97,"text_box_position":[[210,10],[280,10],[280,30],[210,30]]},
+	{"text":"payments-7d9f","confidence":0.88,"text_box_position":[[10,50],[150,50],[150,70],[10,70]]},
+	{"text":"NAME","confidence":0.99,"text_box_position":[[10.4,10],[60,10],[60,30.2],[10.4,30.2]]},
+	{"text":"  ","confidence":0.99,"text_box_position":[[0,0],[5,0],[5,5],[0,5]]},
+	{"text":"noise","confidence":0.2,"text_box_position":[[300,90],[340,90],[340,100],[300,100]]}
+]}]}`
+
+func TestPaddleMapsBoxesToReadingOrder(t *testing.T) {
+	stub := newPaddleStub(t, paddleUnorderedResponse)
+	engine := newTestPaddle(t, stub, map[string]string{"min_confidence": "0.5"})
+
+	image := []byte("fake png bytes")
+	result, err := engine.Recognize(context.Background(), image)
+	if err != nil {
+		t.Fatalf("Recognize: %v", err)
+	}
+
+	if len(stub.request.Images) != 1 || stub.request.Images[0] != base64.StdEncoding.EncodeToString(image) {
+		t.Errorf("images = %q, want base64 of the image", stub.request.Images)
+	}
+
+	if len(result.Lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %+v", len(result.Lines), result.Lines)
+	}
+	if text := result.Lines[0].Text(); text != "NAME STATUS" {
+		t.Errorf("line 1 = %q, want %q", text, "NAME STATUS")
+	}
+	if text := result.Lines[1].Text(); text != "payments-7d9f Running" {
+		t.Errorf("line 2 = %q, want %q", text, "payments-7d9f Running")
+	}
+
+	// Четырехугольник переводится в охватывающий прямоугольник с округлением наружу
+	name := result.Lines[0].Words[0]
+	if want := (BoundingBox{Left: 10, Top: 10, Right: 60, Bottom: 31}); name.Box != want {
+		t.Errorf("NAME box = %+v, want %+v", name.Box, want)
+	}
+	running := result.Lines[1].Words[1]
+	if want := (BoundingBox{Left: 210, Top: 50, Right: 291, Bottom: 72}); running.Box != want {
+		t.Errorf("Running box = %+v, want %+v", running.Box, want)
+	}
+	if !closeTo(running.Confidence, 0.93) {
+		t.Errorf("Running confidence = %v", running.Confidence)
+	}
+}
+
+func TestPaddleSendsLangAndAngleClassifier(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      map[string]string
+		lang        string
+		useAngleCls bool
+	}{
+		{name: "defaults", config: nil, lang: "en", useAngleCls: true},
+		{name: "configured", config: map[string]string{"lang": "ru", "use_angle_cls": "false"}, lang: "ru", useAngleCls: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newPaddleStub(t, `{"status":"000","results":[{"data":[]}]}`)
+			engine := newTestPaddle(t, stub, tt.config)
+
+			if _, err := engine.Recognize(context.Background(), []byte("png")); err != nil {
+				t.Fatalf("Recognize: %v", err)
+			}
+			if stub.request.Lang != tt.lang || stub.request.UseAngleCls != tt.useAngleCls {
+				t.Errorf("lang = %q, use_angle_cls = %t; want %q, %t",
+					stub.request.Lang, stub.request.UseAngleCls, tt.lang, tt.useAngleCls)
+			}
+		})
+	}
+}
+
+func TestPaddleErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		want     string
+	}{
+		{name: "http status", status: http.StatusServiceUnavailable, response: "model is loading", want: "status 503: model is loading"},
+		{name: "malformed json", status: http.StatusOK, response: `{"status":"000","results":[`, want: "decode failed"},
+		{name: "serving error", status: http.StatusOK, response: `{"status":"101","msg":"image decode error"}`, want: "status 101): image decode error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newPaddleStub(t, tt.response)
+			stub.status = tt.status
+			engine := newTestPaddle(t, stub, nil)
+
+			_, err := engine.Recognize(context.Background(), []byte("png"))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package vision
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/jpeg" // регистрирует декодер JPEG для image.DecodeConfig
+	_ "image/png"  // регистрирует декодер PNG для image.DecodeConfig
+	"time"
+)
+
+// Screenshot - захваченный кадр экрана с метаданными
+type Screenshot struct {
+	Data       []byte
+	Format     string // "png" или "jpeg"
+	Width      int
+	Height     int
+	CapturedAt time.Time
+	App        string // приложение, из которого сделан снимок, если известно
+	Source     string // источник кадра: "dir", "upload", "command", "mock"
+	Trigger    string // причина внепланового снимка (см. CaptureRequest), пусто для обычных кадров
+	Note       string // комментарий пользователя к снимку
+
+	// Changed - области, изменившиеся относительно предыдущего кадра того же источника;
+	// nil означает, что распознавать нужно весь кадр
+	Changed []image.Rectangle
+
+	image image.Image // декодированный кадр, заполняется при дедупликации
+}
+
+// key идентифицирует поток кадров для дедупликации: один источник и одно приложение
+func (s Screenshot) key() string {
+	return s.Source + "/" + s.App
+}
+
+// ScreenshotSource - источник скриншотов. Run блокируется до отмены ctx или Close
+// и передает каждый новый кадр в emit; emit возвращает false, если кадр отброшен.
+type ScreenshotSource interface {
+	Run(ctx context.Context, emit func(Screenshot) bool) error
+	Close() error
+}
+
+// NewScreenshotSource создает источник по типу из конфига:
+//   - "dir": новые PNG/JPEG файлы в каталоге path (например, от Flameshot или скрипта)
+//   - "upload": HTTP загрузка через UI сервер (POST /screenshot)
+func NewScreenshotSource(sourceType, path, app string, pollInterval time.Duration) (ScreenshotSource, error) {
+	switch sourceType {
+	case "dir":
+		if path == "" {
+			return nil, fmt.Errorf("vision.source_path is required for dir source")
+		}
+		return NewDirectorySource(path, app, pollInterval), nil
+	case "upload":
+		return NewUploadSource(app), nil
+	default:
+		return nil, fmt.Errorf("unknown screenshot source: %s", sourceType)
+	}
+}
+
+// newScreenshot проверяет, что данные - PNG или JPEG, и заполняет формат и размеры
+func newScreenshot(data []byte, source, app string, capturedAt time.Time) (Screenshot, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Screenshot{}, fmt.Errorf("unsupported screenshot image (PNG or JPEG expected): %w", err)
+	}
+
+	return Screenshot{
+		Data:       data,
+		Format:     format,
+		Width:      config.Width,
+		Height:     config.Height,
+		CapturedAt: capturedAt,
+		App:        app,
+		Source:     source,
+	}, nil
+}
@@ -0,0 +1,183 @@
+package vision
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// TesseractOCR распознает текст, запуская бинарник tesseract на байтах изображения
+//
+// Параметры из ocr_config:
+//   - language: языки распознавания через "+", по умолчанию "eng+rus"
+//   - psm: режим сегментации страницы (--psm), по умолчанию "6" - единый блок текста
+//   - binary: путь к tesseract, по умолчанию ищется в PATH
+//   - tessdata_dir: каталог с моделями (--tessdata-dir), если он нестандартный
+//   - min_confidence: слова с уверенностью ниже порога (0-100) отбрасываются, по умолчанию 0
+type TesseractOCR struct {
+	binary        string
+	language      string
+	psm           string
+	tessdataDir   string
+	minConfidence float64
+}
+
+func NewTesseractOCR(config map[string]string) *TesseractOCR {
+	binary := config["binary"]
+	if binary == "" {
+		binary = "tesseract"
+	}
+	language := config["language"]
+	if language == "" {
+		language = "eng+rus"
+	}
+	psm := config["psm"]
+	if psm == "" {
+		psm = "6"
+	}
+	minConfidence, err := strconv.ParseFloat(config["min_confidence"], 64)
+	if err != nil || minConfidence < 0 {
+		minConfidence = 0
+	}
+
+	return &TesseractOCR{
+		binary:        binary,
+		language:      language,
+		psm:           psm,
+		tessdataDir:   config["tessdata_dir"],
+		minConfidence: minConfidence,
+	}
+}
+
+func (t *TesseractOCR) Initialize() error {
+	path, err := exec.LookPath(t.binary)
+	if err != nil {
+		return fmt.Errorf("tesseract binary %q not found (install tesseract-ocr or set vision.ocr_config.binary): %w", t.binary, err)
+	}
+	if _, err := strconv.Atoi(t.psm); err != nil {
+		return fmt.Errorf("invalid tesseract psm %q: %w", t.psm, err)
+	}
+
+	t.binary = path
+	log.Printf("📸 TesseractOCR initialized (binary: %s, language: %s, psm: %s)", t.binary, t.language, t.psm)
+	return nil
+}
+
+// Recognize запускает tesseract в режиме TSV и собирает слова в строки по координатам
+func (t *TesseractOCR) Recognize(ctx context.Context, imageData []byte) (OCRResult, error) {
+	if len(imageData) == 0 {
+		return OCRResult{}, nil
+	}
+
+	// "stdin stdout" - изображение читается из stdin, результат пишется в stdout
+	args := []string{"stdin", "stdout", "-l", t.language, "--psm", t.psm}
+	if t.tessdataDir != "" {
+		args = append(args, "--tessdata-dir", t.tessdataDir)
+	}
+	args = append(args, "tsv")
+
+	cmd := exec.CommandContext(ctx, t.binary, args...)
+	cmd.Stdin = bytes.NewReader(imageData)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return OCRResult{}, ctx.Err()
+		}
+		if errors.Is(err, exec.ErrNotFound) {
+			return OCRResult{}, fmt.Errorf("tesseract binary %q not found: %w", t.binary, err)
+		}
+		return OCRResult{}, fmt.Errorf("tesseract failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	words, err := parseTesseractTSV(&stdout)
+	if err != nil {
+		return OCRResult{}, err
+	}
+
+	kept := words[:0]
+	for _, word := range words {
+		if word.Confidence*100 >= t.minConfidence {
+			kept = append(kept, word)
+		}
+	}
+	return newOCRResult(kept), nil
+}
+
+// parseTesseractTSV разбирает TSV вывод tesseract. Колонки:
+// level page_num block_num par_num line_num word_num left top width height conf text.
+// Берутся только строки уровня 5 (слово) с непустым текстом, уверенность переводится в 0-1.
+func parseTesseractTSV(output *bytes.Buffer) ([]OCRWord, error) {
+	const (
+		columns   = 12
+		wordLevel = "5"
+	)
+
+	var words []OCRWord
+	scanner := bufio.NewScanner(output)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	header := true
+	for scanner.Scan() {
+		line := scanner.Text()
+		if header {
+			header = false
+			if !strings.HasPrefix(line, "level") {
+				return nil, fmt.Errorf("unexpected tesseract TSV header: %q", line)
+			}
+			continue
+		}
+
+		fields := strings.SplitN(line, "\t", columns)
+		if len(fields) < columns || fields[0] != wordLevel {
+			continue
+		}
+		text := strings.TrimSpace(fields[11])
+		if text == "" {
+			continue
+		}
+
+		// left, top, width, height
+		var numbers [4]int
+		for i := range numbers {
+			n, err := strconv.Atoi(fields[i+6])
+			if err != nil {
+				return nil, fmt.Errorf("invalid tesseract TSV row %q: %w", line, err)
+			}
+			numbers[i] = n
+		}
+		confidence, err := strconv.ParseFloat(fields[10], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tesseract confidence %q: %w", fields[10], err)
+		}
+
+		words = append(words, OCRWord{
+			Text: text,
+			Box: BoundingBox{
+				Left:   numbers[0],
+				Top:    numbers[1],
+				Right:  numbers[0] + numbers[2],
+				Bottom: numbers[1] + numbers[3],
+			},
+			Confidence: confidence / 100,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read tesseract output: %w", err)
+	}
+
+	return words, nil
+}
+
+func (t *TesseractOCR) Close() error {
+	log.Println("📸 TesseractOCR closed")
+	return nil
+}
@@ -0,0 +1,155 @@
+package vision
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// Вывод tesseract ... tsv для двух строк: "kubectl get pods" и "payments Running"
+const fakeTesseractTSV = "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n" +
+	"1\t1\t0\t0\t0\t0\t0\t0\t400\t100\t-1\t\n" +
+	"4\t1\t1\t1\t1\t0\t10\t10\t300\t20\t-1\t\n" +
+	"5\t1\t1\t1\t1\t1\t10\t10\t80\t20\t96.5\tkubectl\n" +
+	"5\t1\t1\t1\t1\t2\t100\t10\t30\t20\t91\tget\n" +
+	"5\t1\t1\t1\t1\t3\t140\t12\t40\t18\t88\tpods\n" +
+	"5\t1\t1\t1\t1\t4\t190\t10\t10\t20\t95\t \n" +
+	"4\t1\t1\t1\t2\t0\t10\t50\t200\t20\t-1\t\n" +
+	"5\t1\t1\t1\t2\t1\t10\t50\t90\t20\t42\tpayments\n" +
+	"5\t1\t1\t1\t2\t2\t110\t50\t70\t20\t85\tRunning\n"
+
+// installFakeTesseract кладет в PATH скрипт tesseract, который сохраняет аргументы и stdin
+// в dir и печатает fakeTesseractTSV
+func installFakeTesseract(t *testing.T) (argsFile, stdinFile string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tesseract is a shell script")
+	}
+
+	dir := t.TempDir()
+	argsFile = filepath.Join(dir, "args")
+	stdinFile = filepath.Join(dir, "stdin")
+	tsvFile := filepath.Join(dir, "out.tsv")
+	if err := os.WriteFile(tsvFile, []byte(fakeTesseractTSV), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	script := "#!/bin/sh\n" +
+		"printf '%s\\n' \"$@\" > '" + argsFile + "'\n" +
+		"cat > '" + stdinFile + "'\n" +
+		"cat '" + tsvFile + "'\n"
+	if err := os.WriteFile(filepath.Join(dir, "tesseract"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return argsFile, stdinFile
+}
+
+func TestTesseractRecognizeWords(t *testing.T) {
+	argsFile, stdinFile := installFakeTesseract(t)
+
+	engine, err := NewOCREngine("tesseract", map[string]string{"psm": "11"})
+	if err != nil {
+		t.Fatalf("NewOCREngine: %v", err)
+	}
+	if err := engine.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+
+	image := []byte("fake png bytes")
+	result, err := engine.Recognize(context.Background(), image)
+	if err != nil {
+		t.Fatalf("Recognize: %v", err)
+	}
+
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotArgs := strings.Fields(string(args))
+	wantArgs := []string{"stdin", "stdout", "-l", "eng+rus", "--psm", "11", "tsv"}
+	if strings.Join(gotArgs, " ") != strings.Join(wantArgs, " ") {
+		t.Errorf("args = %q, want %q", gotArgs, wantArgs)
+	}
+	if stdin, _ := os.ReadFile(stdinFile); string(stdin) != string(image) {
+		t.Errorf("stdin = %q, want image bytes", stdin)
+	}
+
+	if len(result.Lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %+v", len(result.Lines), result.Lines)
+	}
+	if text := result.Lines[0].Text(); text != "kubectl get pods" {
+		t.Errorf("line 1 = %q", text)
+	}
+	if text := result.Lines[1].Text(); text != "payments Running" {
+		t.Errorf("line 2 = %q", text)
+	}
+
+	want := []OCRWord{
+		{Text: "kubectl", Box: BoundingBox{Left: 10, Top: 10, Right: 90, Bottom: 30}, Confidence: 0.965},
+		{Text: "get", Box: BoundingBox{Left: 100, Top: 10, Right: 130, Bottom: 30}, Confidence: 0.91},
+		{Text: "pods", Box: BoundingBox{Left: 140, Top: 12, Right: 180, Bottom: 30}, Confidence: 0.88},
+		{Text: "payments", Box: BoundingBox{Left: 10, Top: 50, Right: 100, Bottom: 70}, Confidence: 0.42},
+		{Text: "Running", Box: BoundingBox{Left: 110, Top: 50, Right: 180, Bottom: 70}, Confidence: 0.85},
+	}
+	var got []OCRWord
+	for _, line := range result.Lines {
+		got = append(got, line.Words...)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d words, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Text != want[i].Text || got[i].Box != want[i].Box || !closeTo(got[i].Confidence, want[i].Confidence) {
+			t.Errorf("word %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestTesseractMinConfidence(t *testing.T) {
+	argsFile, _ := installFakeTesseract(t)
+
+	engine := NewTesseractOCR(map[string]string{
+		"language":       "rus",
+		"min_confidence": "50",
+		"tessdata_dir":   "/opt/tessdata",
+	})
+	if err := engine.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	result, err := engine.Recognize(context.Background(), []byte("png"))
+	if err != nil {
+		t.Fatalf("Recognize: %v", err)
+	}
+
+	args, _ := os.ReadFile(argsFile)
+	wantArgs := "stdin stdout -l rus --psm 6 --tessdata-dir /opt/tessdata tsv"
+	if got := strings.Join(strings.Fields(string(args)), " "); got != wantArgs {
+		t.Errorf("args = %q, want %q", got, wantArgs)
+	}
+	if len(result.Lines) != 2 || result.Lines[1].Text() != "Running" {
+		t.Errorf("low confidence word not dropped: %+v", result.Lines)
+	}
+}
+
+func TestTesseractMissingBinary(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	engine := NewTesseractOCR(nil)
+	err := engine.Initialize()
+	if err == nil {
+		t.Fatal("Initialize succeeded without tesseract on PATH")
+	}
+	if !strings.Contains(err.Error(), `tesseract binary "tesseract" not found`) {
+		t.Errorf("error = %v, want a clear not-found message", err)
+	}
+}
+
+func closeTo(a, b float64) bool {
+	const epsilon = 1e-9
+	return a-b < epsilon && b-a < epsilon
+}
@@ -0,0 +1,144 @@
+package vision
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxUploadSize ограничивает размер загружаемого скриншота
+const maxUploadSize = 20 << 20
+
+// UploadSource принимает скриншоты по HTTP. Обработчик монтируется на UI сервер (POST /screenshot).
+//
+// Изображение передается полем "file" в multipart/form-data или сырым телом запроса
+// (Content-Type: image/png или image/jpeg). Имя приложения - параметр "app" в query или форме,
+// иначе vision.source_app. Доступ ограничивает UI сервер: по умолчанию он слушает только
+// localhost, а с ui.auth_token запрос должен передать токен.
+//
+//	curl -F file=@shot.png -F app=grafana http://localhost:8080/screenshot
+//	curl -H "Authorization: Bearer $TOKEN" --data-binary @shot.png -H "Content-Type: image/png" http://host:8080/screenshot
+type UploadSource struct {
+	app    string
+	mu     sync.Mutex
+	emit   func(Screenshot) bool
+	stopCh chan struct{}
+	once   sync.Once
+}
+
+func NewUploadSource(app string) *UploadSource {
+	return &UploadSource{
+		app:    app,
+		stopCh: make(chan struct{}),
+	}
+}
+
+func (u *UploadSource) Run(ctx context.Context, emit func(Screenshot) bool) error {
+	u.mu.Lock()
+	u.emit = emit
+	u.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+	case <-u.stopCh:
+	}
+
+	u.mu.Lock()
+	u.emit = nil
+	u.mu.Unlock()
+	return nil
+}
+
+func (u *UploadSource) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	data, app, err := u.readUpload(r)
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	shot, err := newScreenshot(data, "upload", app, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	u.mu.Lock()
+	emit := u.emit
+	u.mu.Unlock()
+	if emit == nil || !emit(shot) {
+		http.Error(w, "screenshot queue is not accepting frames", http.StatusServiceUnavailable)
+		return
+	}
+
+	log.Printf("📤 Screenshot uploaded: %s %dx%d from %q", shot.Format, shot.Width, shot.Height, shot.App)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"format": shot.Format,
+		"width":  shot.Width,
+		"height": shot.Height,
+		"app":    shot.App,
+	})
+}
+
+// readUpload извлекает изображение и имя приложения из multipart формы или сырого тела
+func (u *UploadSource) readUpload(r *http.Request) ([]byte, string, error) {
+	app := r.URL.Query().Get("app")
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, "", fmt.Errorf("multipart field \"file\" is required: %w", err)
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return nil, "", err
+		}
+		if formApp := r.PostFormValue("app"); formApp != "" {
+			app = formApp
+		}
+		return data, u.appOrDefault(app), nil
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) == 0 {
+		return nil, "", errors.New("empty request body")
+	}
+	return data, u.appOrDefault(app), nil
+}
+
+func (u *UploadSource) appOrDefault(app string) string {
+	if app == "" {
+		return u.app
+	}
+	return app
+}
+
+func (u *UploadSource) Close() error {
+	u.once.Do(func() { close(u.stopCh) })
+	return nil
+}
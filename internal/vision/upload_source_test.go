@@ -0,0 +1,142 @@
+package vision
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// runningUpload возвращает источник, который принимает кадры так же, как после Run
+func runningUpload(app string, accept bool) (*UploadSource, *[]Screenshot) {
+	var shots []Screenshot
+	u := NewUploadSource(app)
+	u.emit = func(shot Screenshot) bool {
+		if accept {
+			shots = append(shots, shot)
+		}
+		return accept
+	}
+	return u, &shots
+}
+
+func multipartUpload(t *testing.T, data []byte, app string) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, err := form.CreateFormFile("file", "shot.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write(data)
+	if app != "" {
+		form.WriteField("app", app)
+	}
+	form.Close()
+	return &body, form.FormDataContentType()
+}
+
+func TestUploadSourceMultipart(t *testing.T) {
+	u, shots := runningUpload("default", true)
+	body, contentType := multipartUpload(t, testFrame(t, 8, 4).Data, "grafana")
+
+	r := httptest.NewRequest(http.MethodPost, "/screenshot?app=ignored", body)
+	r.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	u.ServeHTTP(w, r)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Format string `json:"format"`
+		Width  int    `json:"width"`
+		Height int    `json:"height"`
+		App    string `json:"app"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Format != "png" || resp.Width != 8 || resp.Height != 4 || resp.App != "grafana" {
+		t.Errorf("response = %+v", resp)
+	}
+	if len(*shots) != 1 || (*shots)[0].Source != "upload" || (*shots)[0].App != "grafana" {
+		t.Errorf("emitted %+v", *shots)
+	}
+}
+
+func TestUploadSourceRawBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		wantApp string
+	}{
+		{name: "app from query", query: "?app=kibana", wantApp: "kibana"},
+		{name: "default app", wantApp: "terminal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, shots := runningUpload("terminal", true)
+			r := httptest.NewRequest(http.MethodPost, "/screenshot"+tt.query, bytes.NewReader(testFrame(t, 8, 4).Data))
+			r.Header.Set("Content-Type", "image/png")
+			w := httptest.NewRecorder()
+			u.ServeHTTP(w, r)
+
+			if w.Code != http.StatusAccepted {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+			if len(*shots) != 1 || (*shots)[0].App != tt.wantApp {
+				t.Errorf("emitted %+v, want app %q", *shots, tt.wantApp)
+			}
+		})
+	}
+}
+
+func TestUploadSourceRejects(t *testing.T) {
+	frame := testFrame(t, 8, 4).Data
+	tests := []struct {
+		name   string
+		method string
+		body   []byte
+		accept bool
+		want   int
+	}{
+		{name: "get", method: http.MethodGet, accept: true, want: http.StatusMethodNotAllowed},
+		{name: "empty body", method: http.MethodPost, accept: true, want: http.StatusBadRequest},
+		{name: "not an image", method: http.MethodPost, body: []byte("hello"), accept: true, want: http.StatusUnsupportedMediaType},
+		{name: "too large", method: http.MethodPost, body: make([]byte, maxUploadSize+1), accept: true, want: http.StatusRequestEntityTooLarge},
+		{name: "queue full", method: http.MethodPost, body: frame, want: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, shots := runningUpload("", tt.accept)
+			r := httptest.NewRequest(tt.method, "/screenshot", bytes.NewReader(tt.body))
+			r.Header.Set("Content-Type", "image/png")
+			w := httptest.NewRecorder()
+			u.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d (%s), want %d", w.Code, strings.TrimSpace(w.Body.String()), tt.want)
+			}
+			if len(*shots) != 0 {
+				t.Errorf("emitted %d screenshots", len(*shots))
+			}
+		})
+	}
+}
+
+func TestUploadSourceNotRunning(t *testing.T) {
+	u := NewUploadSource("")
+	r := httptest.NewRequest(http.MethodPost, "/screenshot", bytes.NewReader(testFrame(t, 8, 4).Data))
+	w := httptest.NewRecorder()
+	u.ServeHTTP(w, r)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503 before Run", w.Code)
+	}
+}
@@ -0,0 +1,122 @@
+package vision
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const defaultFocusPoll = 500 * time.Millisecond
+
+// WindowInfo - окно, находящееся в фокусе
+type WindowInfo struct {
+	ID    string // идентификатор окна в оконной системе; пустой, если фокуса нет
+	Title string
+	Class string // класс приложения (WM_CLASS), если известен
+}
+
+// WindowWatcher сообщает, какое окно сейчас в фокусе
+type WindowWatcher interface {
+	ActiveWindow(ctx context.Context) (WindowInfo, error)
+	Initialize() error
+	Close() error
+}
+
+// NewWindowWatcher создает бэкенд по типу из конфига:
+//   - "x11": свойство _NET_ACTIVE_WINDOW корневого окна X11 (через xprop)
+//   - "fake": заголовок окна читается из текстового файла path - для headless машин и тестов
+func NewWindowWatcher(watcherType, path string) (WindowWatcher, error) {
+	switch watcherType {
+	case "x11":
+		return NewX11WindowWatcher(), nil
+	case "fake":
+		if path == "" {
+			return nil, fmt.Errorf("vision.window_file is required for fake window watcher")
+		}
+		return NewFakeWindowWatcher(path), nil
+	default:
+		return nil, fmt.Errorf("unknown window watcher: %s", watcherType)
+	}
+}
+
+// matchMonitoredApp возвращает имя приложения из vision.monitored_apps, которому
+// соответствует окно (подстрока заголовка или класса без учета регистра)
+func matchMonitoredApp(window WindowInfo, apps []string) string {
+	title := strings.ToLower(window.Title)
+	class := strings.ToLower(window.Class)
+	for _, app := range apps {
+		needle := strings.ToLower(strings.TrimSpace(app))
+		if needle == "" {
+			continue
+		}
+		if strings.Contains(title, needle) || strings.Contains(class, needle) {
+			return strings.TrimSpace(app)
+		}
+	}
+	return ""
+}
+
+// watchFocus опрашивает окно в фокусе и запрашивает снимок, когда фокус переходит
+// в отслеживаемое приложение: другое окно или другое приложение в том же окне (вкладка
+// браузера). Смена заголовка, после которой приложение то же (приглашение терминала,
+// навигация по Grafana), снимка не вызывает. Имя приложения в фокусе подставляется
+// в скриншоты без App.
+func (m *Module) watchFocus(ctx context.Context) {
+	interval := time.Duration(m.cfg.FocusPollMs) * time.Millisecond
+	if interval <= 0 {
+		interval = defaultFocusPoll
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastID, lastApp string
+	failing := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		window, err := m.windows.ActiveWindow(ctx)
+		if err != nil {
+			// Ошибку пишем один раз, а не на каждом опросе
+			if !failing {
+				log.Printf("⚠️  Active window lookup failed: %v", err)
+				failing = true
+			}
+			continue
+		}
+		failing = false
+
+		app := matchMonitoredApp(window, m.cfg.MonitoredApps)
+		if window.ID == lastID && app == lastApp {
+			continue
+		}
+		lastID, lastApp = window.ID, app
+
+		m.mu.Lock()
+		m.focusApp = app
+		m.mu.Unlock()
+
+		if app == "" || m.paused.Load() {
+			continue
+		}
+
+		log.Printf("🪟 Focus moved to %s: %q", app, window.Title)
+		if err := m.Capture(ctx, CaptureRequest{Trigger: "focus", App: app}); err != nil {
+			log.Printf("⚠️  Focus capture failed: %v", err)
+		}
+	}
+}
+
+// FocusedApp возвращает отслеживаемое приложение, находящееся в фокусе, или пустую строку
+func (m *Module) FocusedApp() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.focusApp
+}
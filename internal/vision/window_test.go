@@ -0,0 +1,220 @@
+package vision
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"cluely/internal/config"
+)
+
+// setFakeWindow подменяет заголовок окна для FakeWindowWatcher; запись через rename,
+// чтобы опрос не прочитал файл наполовину
+func setFakeWindow(t *testing.T, path, title string) {
+	t.Helper()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(title+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// setFakeWindowID подменяет заголовок окна с заданным идентификатором
+func setFakeWindowID(t *testing.T, path, title, id string) {
+	t.Helper()
+	setFakeWindow(t, path, title+"\n"+id)
+}
+
+func expectNoScreenshot(t *testing.T, screenshots <-chan Screenshot, wait time.Duration) {
+	t.Helper()
+	select {
+	case shot := <-screenshots:
+		t.Fatalf("unexpected screenshot: trigger %q, app %q", shot.Trigger, shot.App)
+	case <-time.After(wait):
+	}
+}
+
+func expectFocusScreenshot(t *testing.T, screenshots <-chan Screenshot, app string) {
+	t.Helper()
+	select {
+	case shot := <-screenshots:
+		if shot.Trigger != "focus" || shot.App != app {
+			t.Fatalf("screenshot trigger %q, app %q; want focus, %q", shot.Trigger, shot.App, app)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no focus capture for %q", app)
+	}
+}
+
+func TestWatchFocusCapturesOnFocusChange(t *testing.T) {
+	windowFile := filepath.Join(t.TempDir(), "window")
+	m := NewModule(config.VisionConfig{
+		Enabled:       true,
+		OCREngine:     "mock",
+		MonitoredApps: []string{"Grafana", "kubectl"},
+		WindowWatcher: "fake",
+		WindowFile:    windowFile,
+		FocusPollMs:   5,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := m.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer m.Stop()
+	screenshots := m.ScreenshotChannel()
+	poll := 50 * time.Millisecond
+
+	setFakeWindow(t, windowFile, "Slack - #general")
+	expectNoScreenshot(t, screenshots, poll)
+	if app := m.FocusedApp(); app != "" {
+		t.Errorf("FocusedApp = %q for unmonitored window", app)
+	}
+
+	setFakeWindow(t, windowFile, "Grafana - Payments dashboard")
+	expectFocusScreenshot(t, screenshots, "Grafana")
+	// Тот же заголовок на следующих опросах не вызывает новых снимков
+	expectNoScreenshot(t, screenshots, poll)
+	setFakeWindow(t, windowFile, "Grafana - Payments dashboard")
+	expectNoScreenshot(t, screenshots, poll)
+	if app := m.FocusedApp(); app != "Grafana" {
+		t.Errorf("FocusedApp = %q, want Grafana", app)
+	}
+
+	// Кадры без приложения получают приложение в фокусе
+	if !m.publish(m.mockScreenshot("")) {
+		t.Fatal("publish rejected a frame")
+	}
+	if shot := <-screenshots; shot.App != "Grafana" {
+		t.Errorf("published frame app = %q, want Grafana", shot.App)
+	}
+
+	setFakeWindow(t, windowFile, "tmux: kubectl get pods -w")
+	expectFocusScreenshot(t, screenshots, "kubectl")
+
+	// На паузе фокус отслеживается, но снимков нет
+	m.SetPaused(true)
+	setFakeWindow(t, windowFile, "Grafana - Payments dashboard")
+	expectNoScreenshot(t, screenshots, poll)
+	if app := m.FocusedApp(); app != "Grafana" {
+		t.Errorf("FocusedApp = %q while paused, want Grafana", app)
+	}
+}
+
+func TestWatchFocusIgnoresTitleChangesInSameWindow(t *testing.T) {
+	windowFile := filepath.Join(t.TempDir(), "window")
+	m := NewModule(config.VisionConfig{
+		Enabled:       true,
+		OCREngine:     "mock",
+		MonitoredApps: []string{" Grafana ", "kubectl"},
+		WindowWatcher: "fake",
+		WindowFile:    windowFile,
+		FocusPollMs:   5,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := m.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer m.Stop()
+	screenshots := m.ScreenshotChannel()
+	poll := 50 * time.Millisecond
+
+	setFakeWindowID(t, windowFile, "Grafana - Payments dashboard", "0x3a00007")
+	expectFocusScreenshot(t, screenshots, "Grafana")
+
+	// Навигация внутри Grafana меняет заголовок, но не окно и не приложение
+	setFakeWindowID(t, windowFile, "Grafana - Checkout latency", "0x3a00007")
+	expectNoScreenshot(t, screenshots, poll)
+	setFakeWindowID(t, windowFile, "Grafana - Explore", "0x3a00007")
+	expectNoScreenshot(t, screenshots, poll)
+
+	// Вкладка с другим приложением в том же окне браузера - новый снимок
+	setFakeWindowID(t, windowFile, "kubectl docs - Kubernetes", "0x3a00007")
+	expectFocusScreenshot(t, screenshots, "kubectl")
+
+	// Приглашение терминала меняется при каждой команде
+	setFakeWindowID(t, windowFile, "tmux: kubectl get pods", "0x1c00003")
+	expectFocusScreenshot(t, screenshots, "kubectl")
+	setFakeWindowID(t, windowFile, "tmux: kubectl describe pod payments-7d9f", "0x1c00003")
+	expectNoScreenshot(t, screenshots, poll)
+}
+
+func TestParseActiveWindowID(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want string
+	}{
+		{name: "window", out: "_NET_ACTIVE_WINDOW: window id # 0x3a00007\n", want: "0x3a00007"},
+		{name: "several ids", out: "_NET_ACTIVE_WINDOW: window id # 0x1c00003, 0x0\n", want: "0x1c00003"},
+		{name: "no focus", out: "_NET_ACTIVE_WINDOW: window id # 0x0\n", want: ""},
+		{name: "not found", out: "_NET_ACTIVE_WINDOW:  not found.\n", want: ""},
+		{name: "no such atom", out: "_NET_ACTIVE_WINDOW:  no such atom on any window.\n", want: ""},
+		{name: "garbage id", out: "_NET_ACTIVE_WINDOW: window id # zzz\n", want: ""},
+		{name: "empty", out: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseActiveWindowID(tt.out); got != tt.want {
+				t.Errorf("parseActiveWindowID(%q) = %q, want %q", tt.out, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseXpropStrings(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{name: "single", value: ` "Grafana - Payments"`, want: []string{"Grafana - Payments"}},
+		{name: "wm class", value: ` "Navigator", "firefox"`, want: []string{"Navigator", "firefox"}},
+		{name: "escaped quotes", value: ` "say \"hi\" to ops"`, want: []string{`say "hi" to ops`}},
+		{name: "escaped backslash", value: ` "C:\\logs\\", "x"`, want: []string{`C:\logs\`, "x"}},
+		{name: "utf-8", value: ` "Логи — Grafana"`, want: []string{"Логи — Grafana"}},
+		{name: "empty string", value: ` ""`, want: []string{""}},
+		{name: "unterminated", value: ` "half`, want: []string{"half"}},
+		{name: "not found", value: `  not found.`, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseXpropStrings(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseXpropStrings(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchMonitoredApp(t *testing.T) {
+	apps := []string{"", " Grafana ", "kubectl", "firefox"}
+	tests := []struct {
+		name   string
+		window WindowInfo
+		want   string
+	}{
+		{name: "title substring", window: WindowInfo{Title: "Payments - GRAFANA"}, want: "Grafana"},
+		{name: "class", window: WindowInfo{Title: "Mozilla", Class: "Firefox"}, want: "firefox"},
+		{name: "first match wins", window: WindowInfo{Title: "grafana: kubectl"}, want: "Grafana"},
+		{name: "no match", window: WindowInfo{Title: "Slack", Class: "Slack"}, want: ""},
+		{name: "no focus", window: WindowInfo{}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchMonitoredApp(tt.window, apps); got != tt.want {
+				t.Errorf("matchMonitoredApp(%+v) = %q, want %q", tt.window, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package vision
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// X11WindowWatcher читает свойство _NET_ACTIVE_WINDOW корневого окна, а затем заголовок
+// (_NET_WM_NAME, WM_NAME) и класс (WM_CLASS) активного окна. Использует xprop из x11-utils,
+// поэтому работает с любым EWMH-совместимым оконным менеджером без cgo.
+type X11WindowWatcher struct {
+	binary string
+}
+
+func NewX11WindowWatcher() *X11WindowWatcher {
+	return &X11WindowWatcher{binary: "xprop"}
+}
+
+func (x *X11WindowWatcher) Initialize() error {
+	if os.Getenv("DISPLAY") == "" {
+		return errors.New("DISPLAY is not set: x11 window watcher needs an X session")
+	}
+	path, err := exec.LookPath(x.binary)
+	if err != nil {
+		return fmt.Errorf("xprop not found (install x11-utils): %w", err)
+	}
+
+	x.binary = path
+	log.Printf("🪟 X11 window watcher initialized (display: %s)", os.Getenv("DISPLAY"))
+	return nil
+}
+
+func (x *X11WindowWatcher) ActiveWindow(ctx context.Context) (WindowInfo, error) {
+	out, err := x.xprop(ctx, "-root", "_NET_ACTIVE_WINDOW")
+	if err != nil {
+		return WindowInfo{}, err
+	}
+
+	id := parseActiveWindowID(out)
+	if id == "" {
+		return WindowInfo{}, nil
+	}
+
+	out, err = x.xprop(ctx, "-id", id, "_NET_WM_NAME", "WM_NAME", "WM_CLASS")
+	if err != nil {
+		return WindowInfo{}, err
+	}
+
+	window := WindowInfo{ID: id}
+	var wmName string
+	for _, line := range strings.Split(out, "\n") {
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		values := parseXpropStrings(value)
+		if len(values) == 0 {
+			continue
+		}
+
+		switch strings.TrimSpace(name) {
+		case "_NET_WM_NAME":
+			window.Title = values[0]
+		case "WM_NAME":
+			wmName = values[0]
+		case "WM_CLASS":
+			// WM_CLASS = "instance", "class" - нужен класс приложения
+			window.Class = values[len(values)-1]
+		}
+	}
+	if window.Title == "" {
+		window.Title = wmName
+	}
+
+	return window, nil
+}
+
+func (x *X11WindowWatcher) xprop(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, x.binary, append([]string{"-notype"}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("xprop failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// parseActiveWindowID разбирает "_NET_ACTIVE_WINDOW: window id # 0x3a00007".
+// Возвращает пустую строку, если активного окна нет (0x0) или свойство не поддерживается.
+func parseActiveWindowID(out string) string {
+	_, value, ok := strings.Cut(out, "#")
+	if !ok {
+		return ""
+	}
+	fields := strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' || r == '\n' })
+	if len(fields) == 0 {
+		return ""
+	}
+
+	id, err := strconv.ParseUint(strings.TrimPrefix(fields[0], "0x"), 16, 32)
+	if err != nil || id == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// parseXpropStrings разбирает значение строкового свойства: "a", "b" -> [a b]
+func parseXpropStrings(value string) []string {
+	var values []string
+	for {
+		start := strings.IndexByte(value, '"')
+		if start < 0 {
+			return values
+		}
+		value = value[start+1:]
+
+		var b strings.Builder
+		i := 0
+		for ; i < len(value) && value[i] != '"'; i++ {
+			if value[i] == '\\' && i+1 < len(value) {
+				i++
+			}
+			b.WriteByte(value[i])
+		}
+		values = append(values, b.String())
+		if i >= len(value) {
+			return values
+		}
+		value = value[i+1:]
+	}
+}
+
+func (x *X11WindowWatcher) Close() error {
+	return nil
+}